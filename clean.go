@@ -5,7 +5,8 @@ import (
 	"os"
 )
 
-// Cleans the temporary files, which includes the keys, encrypted data and results.
+// Cleans the temporary files, which includes the keys, encrypted data and results,
+// and the folders of the testcases written by suite.go.
 func main() {
 	cleanFolder("temps/")
 }
@@ -15,7 +16,7 @@ func cleanFolder(folderPath string) {
 	files, _ := folder.Readdir(0)
 	for i := range files {
 		if files[i].Name() != "donotremove.txt" {
-			if err := os.Remove(folderPath + files[i].Name()); err != nil {
+			if err := os.RemoveAll(folderPath + files[i].Name()); err != nil {
 				log.Println(err)
 			}
 		}
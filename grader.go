@@ -0,0 +1,95 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"flag"
+	"log"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"time"
+
+	"app/internal/grader"
+)
+
+// Runs a local HTTP service grading the archives of package.go as the
+// platform does: the solution of the archive runs on fresh keys and inputs,
+// drawn with a hidden seed, with the "Challenge" and "Security" blocks of the
+// config.json of the grader, and the report of verify.go is returned as JSON.
+//
+//	curl --data-binary @app.zip "http://localhost:8080/grade?name=candidate"
+//	curl http://localhost:8080/results
+func main() {
+	addr := flag.String("addr", "localhost:8080", "address to listen on")
+	dir := flag.String("dir", "temps/grader", "folder of the submissions, each graded in <dir>/<id>")
+	configFile := flag.String("config", "config.json", "config.json of the platform, whose \"Challenge\" and \"Security\" blocks replace those of the submissions")
+	seed := flag.Int64("seed", 0, "seed of the inputs, drawn at random and kept hidden if not set (a seed given here is visible to the solutions)")
+	timeout := flag.Duration("timeout", 10*time.Minute, "time limit of the solution (0 for no limit)")
+	maxMemory := flag.Uint64("memory", 0, "memory limit of the solution in MiB (0 for no limit)")
+	maxArchive := flag.Int64("max-archive", 32, "maximum size of an archive in MiB")
+	maxSize := flag.Int64("max-size", 256, "maximum uncompressed size of a submission in MiB")
+	keep := flag.Bool("keep", false, "keep the public keys, inputs and sources of the submissions in their folder")
+
+	flag.Parse()
+
+	config, err := os.ReadFile(*configFile)
+	if err != nil {
+		log.Fatalf("os.ReadFile(%s): %s", *configFile, err.Error())
+	}
+
+	seeded := false
+	flag.Visit(func(f *flag.Flag) {
+		seeded = seeded || f.Name == "seed"
+	})
+
+	if !seeded {
+		var b [8]byte
+		if _, err := rand.Read(b[:]); err != nil {
+			log.Fatalf("crypto/rand.Read: %s", err.Error())
+		}
+		*seed = int64(binary.LittleEndian.Uint64(b[:]))
+	}
+
+	// setup.go and verify.go are those of the grader, not of the submissions
+	bin, err := filepath.Abs(filepath.Join(*dir, "bin"))
+	if err != nil {
+		log.Fatalf("filepath.Abs: %s", err.Error())
+	}
+
+	for _, prog := range []string{"setup", "verify"} {
+		/* #nosec G204 */
+		build := exec.Command("go", "build", "-o", filepath.Join(bin, prog), prog+".go")
+		build.Stdout, build.Stderr = os.Stdout, os.Stderr
+		if err := build.Run(); err != nil {
+			log.Fatalf("go build %s.go: %s", prog, err.Error())
+		}
+	}
+
+	g := &grader.Grader{
+		Dir:       *dir,
+		Setup:     filepath.Join(bin, "setup"),
+		Verify:    filepath.Join(bin, "verify"),
+		Config:    config,
+		Seed:      *seed,
+		Timeout:   *timeout,
+		MaxMemory: *maxMemory << 20,
+		MaxSize:   *maxSize << 20,
+		Keep:      *keep,
+	}
+
+	logger := log.Default()
+
+	server := &http.Server{
+		Addr:              *addr,
+		Handler:           g.Handler(*maxArchive<<20, logger),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	logger.Printf("grading the submissions on http://%s/grade, results on http://%s/results", *addr, *addr)
+
+	if err := server.ListenAndServe(); err != nil {
+		log.Fatalf("http.Server.ListenAndServe: %s", err.Error())
+	}
+}
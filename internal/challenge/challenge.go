@@ -0,0 +1,203 @@
+// Package challenge defines the tasks a solution can be asked to solve.
+// A challenge is shared by setup.go, which generates and encrypts its input,
+// and verify.go, which compares the decrypted output against its reference.
+package challenge
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"sort"
+
+	"github.com/tuneinsight/lattigo/v5/he/hefloat"
+	"github.com/tuneinsight/lattigo/v5/ring"
+	"github.com/tuneinsight/lattigo/v5/utils"
+)
+
+// Input and Output are the names of the input and of the output of the
+// challenges with a single operand.
+const (
+	Input  = "in"
+	Output = "out"
+)
+
+// Values are the cleartext inputs or outputs of a testcase, by name.
+// A vector longer than Layout.Slots is split across several ciphertexts
+// (see utils.CiphertextVector).
+type Values map[string][]complex128
+
+// Names returns the sorted names of the vectors.
+func (v Values) Names() (names []string) {
+	for name := range v {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return
+}
+
+// Layout describes how the values of a testcase are packed in the slots.
+type Layout struct {
+	Slots   int  // Number of slots carrying data in each ciphertext, the remaining slots are ignored.
+	Complex bool // The values have an imaginary part, which the real slots of ring.ConjugateInvariant cannot hold.
+}
+
+// Challenge is the definition of a task.
+type Challenge interface {
+	// GenInput returns the cleartext inputs of a testcase.
+	GenInput(params hefloat.Parameters, r *rand.Rand) (in Values, err error)
+
+	// Reference returns the expected outputs for the given inputs.
+	Reference(in Values) (want Values)
+
+	// Layout returns the slot layout of the inputs and of the outputs.
+	Layout(params hefloat.Parameters) Layout
+
+	// Threshold returns the minimum precision, in bits, a solution must achieve.
+	Threshold() float64
+}
+
+// CheckParameters returns an error if the slots of the parameters cannot hold the values of the challenge.
+func CheckParameters(c Challenge, params hefloat.Parameters) error {
+	if c.Layout(params).Complex && params.RingType() == ring.ConjugateInvariant {
+		return fmt.Errorf("the challenge has complex values but the parameters have RingType=ConjugateInvariant, whose %d slots are real: use RingType=Standard", params.MaxSlots())
+	}
+	return nil
+}
+
+// Literal is the "Challenge" block of config.json.
+type Literal struct {
+	Name         string                         // Default: "parity"
+	MinPrecision *float64                       `json:",omitempty"` // Default: the challenge's own threshold
+	Length       *int                           `json:",omitempty"` // Number of values of each input, default: Layout.Slots (one ciphertext)
+	Seed         int64                          `json:",omitempty"` // Seed of the inputs, default: 0
+	Inputs       map[string]DistributionLiteral `json:",omitempty"` // Distribution of the inputs by name, default: uniform in the interval of the challenge
+}
+
+// lengthSetter is implemented by the challenges accepting the "Length" field.
+type lengthSetter interface {
+	SetLength(length int)
+}
+
+// distributionSetter is implemented by the challenges accepting the "Inputs" field.
+type distributionSetter interface {
+	SetDistribution(name string, lit DistributionLiteral) error
+}
+
+// DefaultName is the challenge used when config.json does not specify one.
+const DefaultName = "parity"
+
+var registry = map[string]func() Challenge{}
+
+// Register makes a challenge available under the given name.
+// It panics if the name is already taken.
+func Register(name string, newChallenge func() Challenge) {
+	if _, ok := registry[name]; ok {
+		panic(fmt.Errorf("challenge.Register: %s is already registered", name))
+	}
+	registry[name] = newChallenge
+}
+
+// Names returns the sorted names of the registered challenges.
+func Names() (names []string) {
+	for name := range registry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return
+}
+
+// Get returns the challenge registered under the given name.
+func Get(name string) (Challenge, error) {
+	newChallenge, ok := registry[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown challenge %q, available challenges are %v", name, Names())
+	}
+	return newChallenge(), nil
+}
+
+// LiteralFromJSON returns the "Challenge" block of config.json with its defaults set.
+func LiteralFromJSON(data []byte) (lit Literal, err error) {
+
+	aux := struct {
+		Challenge Literal
+	}{}
+
+	if err = json.Unmarshal(data, &aux); err != nil {
+		return
+	}
+
+	lit = aux.Challenge
+
+	if lit.Name == "" {
+		lit.Name = DefaultName
+	}
+
+	return
+}
+
+// Challenge returns the challenge described by the literal.
+func (lit Literal) Challenge() (c Challenge, err error) {
+
+	if c, err = Get(lit.Name); err != nil {
+		return
+	}
+
+	if lit.Length != nil {
+
+		setter, ok := c.(lengthSetter)
+		if !ok {
+			return nil, fmt.Errorf("challenge %q does not accept a Length", lit.Name)
+		}
+
+		if *lit.Length < 1 {
+			return nil, fmt.Errorf("invalid Length=%d for challenge %q", *lit.Length, lit.Name)
+		}
+
+		setter.SetLength(*lit.Length)
+	}
+
+	if len(lit.Inputs) != 0 {
+
+		setter, ok := c.(distributionSetter)
+		if !ok {
+			return nil, fmt.Errorf("challenge %q does not accept Inputs", lit.Name)
+		}
+
+		for _, name := range utils.GetSortedKeys(lit.Inputs) {
+			if err = setter.SetDistribution(name, lit.Inputs[name]); err != nil {
+				return nil, fmt.Errorf("challenge %q: Inputs: %s: %w", lit.Name, name, err)
+			}
+		}
+	}
+
+	if lit.MinPrecision != nil {
+		c = withThreshold{Challenge: c, minPrecision: *lit.MinPrecision}
+	}
+
+	return
+}
+
+// Rand returns the source of the inputs, seeded with Seed.
+func (lit Literal) Rand() *rand.Rand {
+	/* #nosec G404 */
+	return rand.New(rand.NewSource(lit.Seed))
+}
+
+// FromJSON returns the challenge selected by the "Challenge" block of config.json.
+func FromJSON(data []byte) (Challenge, error) {
+	lit, err := LiteralFromJSON(data)
+	if err != nil {
+		return nil, err
+	}
+	return lit.Challenge()
+}
+
+// withThreshold overrides the precision threshold of a challenge.
+type withThreshold struct {
+	Challenge
+	minPrecision float64
+}
+
+func (c withThreshold) Threshold() float64 {
+	return c.minPrecision
+}
@@ -0,0 +1,249 @@
+package challenge
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+)
+
+// Distribution generates the values of an input.
+type Distribution interface {
+	// Sample returns n values, with an imaginary part if imag is set.
+	Sample(r *rand.Rand, n int, imag bool) ([]complex128, error)
+}
+
+// DistributionLiteral is an entry of the "Inputs" field of the "Challenge"
+// block of config.json. The interval [A, B] defaults to the one of the challenge.
+type DistributionLiteral struct {
+	Type    string   // "Uniform" (default), "Gaussian", "Integer", "Sparse", "Edge" or "File"
+	A       *float64 `json:",omitempty"` // Lower bound of the interval (Uniform, Integer, Sparse, Edge)
+	B       *float64 `json:",omitempty"` // Upper bound of the interval (Uniform, Integer, Sparse, Edge)
+	Mean    float64  `json:",omitempty"` // Mean (Gaussian)
+	Sigma   float64  `json:",omitempty"` // Standard deviation (Gaussian)
+	Density float64  `json:",omitempty"` // Fraction of non-zero values (Sparse)
+	Epsilon float64  `json:",omitempty"` // Maximum distance to the edges, default 2^-10 (Edge)
+	Path    string   `json:",omitempty"` // CSV or NumPy .npy file (File)
+	Seed    *int64   `json:",omitempty"` // Seed of the input, default: drawn from the seed of the challenge
+}
+
+// DefaultEpsilon is the default maximum distance of Edge values to the edges.
+const DefaultEpsilon = 0x1p-10
+
+// Distribution returns the distribution described by the literal, with
+// [a, b] as the default interval.
+func (lit DistributionLiteral) Distribution(a, b float64) (d Distribution, err error) {
+
+	if lit.A != nil {
+		a = *lit.A
+	}
+
+	if lit.B != nil {
+		b = *lit.B
+	}
+
+	if a > b {
+		return nil, fmt.Errorf("invalid interval [%v, %v]", a, b)
+	}
+
+	switch lit.Type {
+	case "", "Uniform":
+		d = Uniform{A: a, B: b}
+	case "Gaussian":
+		if lit.Sigma <= 0 {
+			return nil, fmt.Errorf("invalid Sigma=%v for Type=Gaussian, must be positive", lit.Sigma)
+		}
+		d = Gaussian{Mean: lit.Mean, Sigma: lit.Sigma}
+	case "Integer":
+		if math.Ceil(a) > math.Floor(b) {
+			return nil, fmt.Errorf("invalid interval [%v, %v] for Type=Integer, it contains no integer", a, b)
+		}
+		d = Integer{A: int64(math.Ceil(a)), B: int64(math.Floor(b))}
+	case "Sparse":
+		if lit.Density <= 0 || lit.Density > 1 {
+			return nil, fmt.Errorf("invalid Density=%v for Type=Sparse, must be in (0, 1]", lit.Density)
+		}
+		d = Sparse{Uniform: Uniform{A: a, B: b}, Density: lit.Density}
+	case "Edge":
+		epsilon := lit.Epsilon
+		if epsilon == 0 {
+			epsilon = DefaultEpsilon
+		}
+		if epsilon < 0 {
+			return nil, fmt.Errorf("invalid Epsilon=%v for Type=Edge, must be positive", epsilon)
+		}
+		d = Edge{A: a, B: b, Epsilon: epsilon}
+	case "File":
+		var values []complex128
+		if values, err = ReadValues(lit.Path); err != nil {
+			return nil, err
+		}
+		d = File{Path: lit.Path, Values: values}
+	default:
+		return nil, fmt.Errorf("unknown distribution Type=%q, available types are [Uniform Gaussian Integer Sparse Edge File]", lit.Type)
+	}
+
+	if lit.Seed != nil {
+		d = Seeded{Distribution: d, Seed: *lit.Seed}
+	}
+
+	return
+}
+
+// Uniform samples values uniformly in [A, B].
+type Uniform struct {
+	A, B float64
+}
+
+// Sample returns n values sampled uniformly in [A, B], with an imaginary part also sampled in [A, B] if imag is set.
+func (d Uniform) Sample(r *rand.Rand, n int, imag bool) ([]complex128, error) {
+	return sampleParts(n, imag, func() float64 {
+		return (d.B-d.A)*r.Float64() + d.A
+	}), nil
+}
+
+// Gaussian samples values from the normal distribution of mean Mean and standard deviation Sigma.
+type Gaussian struct {
+	Mean, Sigma float64
+}
+
+// Sample returns n values sampled from the normal distribution, the imaginary part independently if imag is set.
+func (d Gaussian) Sample(r *rand.Rand, n int, imag bool) ([]complex128, error) {
+	return sampleParts(n, imag, func() float64 {
+		return d.Sigma*r.NormFloat64() + d.Mean
+	}), nil
+}
+
+// Integer samples integers uniformly in [A, B].
+type Integer struct {
+	A, B int64
+}
+
+// Sample returns n integers sampled uniformly in [A, B], the imaginary part independently if imag is set.
+func (d Integer) Sample(r *rand.Rand, n int, imag bool) ([]complex128, error) {
+	return sampleParts(n, imag, func() float64 {
+		return float64(d.A + r.Int63n(d.B-d.A+1))
+	}), nil
+}
+
+// Sparse samples values uniformly in [A, B] with probability Density, and zero otherwise.
+type Sparse struct {
+	Uniform
+	Density float64
+}
+
+// Sample returns n values, each being zero with probability 1-Density.
+func (d Sparse) Sample(r *rand.Rand, n int, imag bool) (values []complex128, err error) {
+
+	if values, err = d.Uniform.Sample(r, n, imag); err != nil {
+		return
+	}
+
+	for i := range values {
+		if r.Float64() >= d.Density {
+			values[i] = 0
+		}
+	}
+
+	return
+}
+
+// Edge samples values at distance at most Epsilon of A, B and, if it is
+// in the interval, 0, where approximations are usually the least precise.
+type Edge struct {
+	A, B, Epsilon float64
+}
+
+// Sample returns n values in [A, B] close to its edges, the imaginary part independently if imag is set.
+func (d Edge) Sample(r *rand.Rand, n int, imag bool) ([]complex128, error) {
+
+	edges := []float64{d.A, d.B}
+	if d.A < 0 && 0 < d.B {
+		edges = append(edges, 0)
+	}
+
+	return sampleParts(n, imag, func() float64 {
+		x := edges[r.Intn(len(edges))] + d.Epsilon*(2*r.Float64()-1)
+		return math.Min(math.Max(x, d.A), d.B)
+	}), nil
+}
+
+// File returns the first values read from a file (see ReadValues).
+type File struct {
+	Path   string
+	Values []complex128
+}
+
+// Sample returns the first n values of the file, or an error if it has fewer.
+// The values are returned as read, regardless of imag.
+func (d File) Sample(r *rand.Rand, n int, imag bool) ([]complex128, error) {
+
+	if n > len(d.Values) {
+		return nil, fmt.Errorf("%s has %d values but %d are needed", d.Path, len(d.Values), n)
+	}
+
+	values := make([]complex128, n)
+	copy(values, d.Values)
+
+	return values, nil
+}
+
+// Seeded samples from Distribution with its own source seeded with Seed,
+// instead of the source of the challenge.
+type Seeded struct {
+	Distribution
+	Seed int64
+}
+
+// Sample samples from Distribution with a source seeded with Seed.
+func (d Seeded) Sample(r *rand.Rand, n int, imag bool) ([]complex128, error) {
+	/* #nosec G404 */
+	return d.Distribution.Sample(rand.New(rand.NewSource(d.Seed)), n, imag)
+}
+
+// Distributions are the distributions of the inputs of a challenge, by name.
+type Distributions map[string]Distribution
+
+// sample returns n values of the input name, sampled uniformly in [a, b] if it has no distribution.
+func (ds Distributions) sample(name string, r *rand.Rand, a, b float64, imag bool, n int) (values []complex128, err error) {
+
+	d, ok := ds[name]
+	if !ok {
+		d = Uniform{A: a, B: b}
+	}
+
+	if values, err = d.Sample(r, n, imag); err != nil {
+		return nil, fmt.Errorf("input %s: %w", name, err)
+	}
+
+	return
+}
+
+// set sets the distribution of the input name, which must be one of inputs.
+func (ds *Distributions) set(name string, d Distribution, inputs ...string) error {
+
+	if i := sort.SearchStrings(inputs, name); i == len(inputs) || inputs[i] != name {
+		return fmt.Errorf("unknown input %q, available inputs are %v", name, inputs)
+	}
+
+	if *ds == nil {
+		*ds = Distributions{}
+	}
+
+	(*ds)[name] = d
+
+	return nil
+}
+
+// sampleParts returns n values whose real part, and imaginary part if imag is set, are drawn with draw.
+func sampleParts(n int, imag bool, draw func() float64) (values []complex128) {
+	values = make([]complex128, n)
+	for i := range values {
+		if imag {
+			values[i] = complex(draw(), draw())
+		} else {
+			values[i] = complex(draw(), 0)
+		}
+	}
+	return
+}
@@ -0,0 +1,176 @@
+package challenge
+
+import (
+	"math"
+	"math/cmplx"
+	"math/rand"
+
+	"github.com/tuneinsight/lattigo/v5/he/hefloat"
+)
+
+func init() {
+	Register("parity", func() Challenge {
+		return &Elementwise{A: -1, B: 1, Complex: true, MinPrecision: 20, F: cmplx.Conj}
+	})
+
+	Register("sign", func() Challenge {
+		return &Elementwise{A: -1, B: 1, MinPrecision: 8, F: func(x complex128) complex128 {
+			switch {
+			case real(x) > 0:
+				return 1
+			case real(x) < 0:
+				return -1
+			default:
+				return 0
+			}
+		}}
+	})
+
+	Register("relu", func() Challenge {
+		return &Elementwise{A: -1, B: 1, MinPrecision: 8, F: func(x complex128) complex128 {
+			return complex(math.Max(real(x), 0), 0)
+		}}
+	})
+
+	Register("inverse", func() Challenge {
+		return &Elementwise{A: 1.0 / 64, B: 1, MinPrecision: 12, F: func(x complex128) complex128 {
+			return 1 / x
+		}}
+	})
+
+	Register("compare", func() Challenge {
+		return &Pairwise{A: -1, B: 1, MinPrecision: 8, F: func(a, b complex128) complex128 {
+			switch {
+			case real(a) > real(b):
+				return 1
+			case real(a) < real(b):
+				return 0
+			default:
+				return 0.5
+			}
+		}}
+	})
+}
+
+// Elementwise is a challenge applying the same function F to every value
+// of the input Input, the result being the output Output.
+type Elementwise struct {
+	A, B         float64       // Interval of the input values.
+	Complex      bool          // If true, the imaginary part of the inputs is also sampled in [A, B].
+	MinPrecision float64       // Minimum precision in bits.
+	Length       int           // Number of input values, Layout.Slots if zero.
+	Inputs       Distributions // Distribution of the input, uniform in [A, B] if not set.
+	F            func(x complex128) complex128
+}
+
+// GenInput samples Length values from the distribution of the input.
+func (c Elementwise) GenInput(params hefloat.Parameters, r *rand.Rand) (in Values, err error) {
+
+	var x []complex128
+	if x, err = c.Inputs.sample(Input, r, c.A, c.B, c.Complex, length(c.Length, c.Layout(params))); err != nil {
+		return
+	}
+
+	return Values{Input: x}, nil
+}
+
+// Reference applies F to every input value.
+func (c Elementwise) Reference(in Values) (want Values) {
+	x := in[Input]
+	y := make([]complex128, len(x))
+	for i := range x {
+		y[i] = c.F(x[i])
+	}
+	return Values{Output: y}
+}
+
+// Layout uses all the slots, which are complex if Complex is set.
+func (c Elementwise) Layout(params hefloat.Parameters) Layout {
+	return Layout{Slots: params.MaxSlots(), Complex: c.Complex}
+}
+
+// Threshold returns MinPrecision.
+func (c Elementwise) Threshold() float64 {
+	return c.MinPrecision
+}
+
+// SetLength sets Length.
+func (c *Elementwise) SetLength(length int) {
+	c.Length = length
+}
+
+// SetDistribution sets the distribution of the input Input, with [A, B] as its default interval.
+func (c *Elementwise) SetDistribution(name string, lit DistributionLiteral) error {
+	d, err := lit.Distribution(c.A, c.B)
+	if err != nil {
+		return err
+	}
+	return c.Inputs.set(name, d, Input)
+}
+
+// Pairwise is a challenge applying the same function F to every pair of
+// values of the inputs "a" and "b", the result being the output Output.
+type Pairwise struct {
+	A, B         float64       // Interval of the input values.
+	MinPrecision float64       // Minimum precision in bits.
+	Length       int           // Number of values of each input, Layout.Slots if zero.
+	Inputs       Distributions // Distribution of the inputs by name, uniform in [A, B] if not set.
+	F            func(a, b complex128) complex128
+}
+
+// GenInput samples Length real values from the distribution of each input.
+func (c Pairwise) GenInput(params hefloat.Parameters, r *rand.Rand) (in Values, err error) {
+
+	n := length(c.Length, c.Layout(params))
+
+	in = Values{}
+	for _, name := range []string{"a", "b"} {
+		if in[name], err = c.Inputs.sample(name, r, c.A, c.B, false, n); err != nil {
+			return nil, err
+		}
+	}
+
+	return
+}
+
+// Reference applies F to every pair of input values.
+func (c Pairwise) Reference(in Values) (want Values) {
+	a, b := in["a"], in["b"]
+	y := make([]complex128, len(a))
+	for i := range a {
+		y[i] = c.F(a[i], b[i])
+	}
+	return Values{Output: y}
+}
+
+// Layout uses all the slots, with real values.
+func (c Pairwise) Layout(params hefloat.Parameters) Layout {
+	return Layout{Slots: params.MaxSlots()}
+}
+
+// Threshold returns MinPrecision.
+func (c Pairwise) Threshold() float64 {
+	return c.MinPrecision
+}
+
+// SetLength sets Length.
+func (c *Pairwise) SetLength(length int) {
+	c.Length = length
+}
+
+// SetDistribution sets the distribution of the input "a" or "b", with [A, B] as its default interval.
+func (c *Pairwise) SetDistribution(name string, lit DistributionLiteral) error {
+	d, err := lit.Distribution(c.A, c.B)
+	if err != nil {
+		return err
+	}
+	return c.Inputs.set(name, d, "a", "b")
+}
+
+// length returns n, or the number of slots of the layout if n is zero.
+func length(n int, layout Layout) int {
+	if n == 0 {
+		return layout.Slots
+	}
+	return n
+}
@@ -0,0 +1,227 @@
+package challenge
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// ReadValues reads the values of a file:
+//   - .csv: one value per record, either "re" or "re,im", lines starting with '#' are ignored.
+//   - .npy: NumPy array of dtype float32, float64, int32, int64 or complex128, flattened in C order.
+//
+// It reads at most MaxValues values.
+func ReadValues(path string) (values []complex128, err error) {
+
+	/* #nosec G304 */
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("os.Open(%s): %w", path, err)
+	}
+	defer f.Close()
+
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".csv":
+		values, err = readCSV(f)
+	case ".npy":
+		values, err = readNPY(bufio.NewReader(f))
+	default:
+		return nil, fmt.Errorf("%s: unsupported extension %q, supported extensions are [.csv .npy]", path, ext)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	return
+}
+
+func readCSV(r io.Reader) (values []complex128, err error) {
+
+	cr := csv.NewReader(r)
+	cr.Comment = '#'
+	cr.FieldsPerRecord = -1
+	cr.TrimLeadingSpace = true
+
+	records, err := cr.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("csv.Reader.ReadAll: %w", err)
+	}
+
+	if len(records) > MaxValues {
+		return nil, fmt.Errorf("more than %d records", MaxValues)
+	}
+
+	values = make([]complex128, len(records))
+	for i, record := range records {
+
+		if len(record) != 1 && len(record) != 2 {
+			return nil, fmt.Errorf("record %d has %d fields, expected 1 (re) or 2 (re, im)", i+1, len(record))
+		}
+
+		var parts [2]float64
+		for j, field := range record {
+			if parts[j], err = strconv.ParseFloat(strings.TrimSpace(field), 64); err != nil {
+				return nil, fmt.Errorf("record %d: %w", i+1, err)
+			}
+		}
+
+		values[i] = complex(parts[0], parts[1])
+	}
+
+	return
+}
+
+// MaxValues is the maximum number of values of a file read by ReadValues.
+const MaxValues = 1 << 24
+
+// npyMaxHeader is the maximum length of a .npy header, which NumPy keeps to a
+// few KiB (a version 1.0 header is at most 64 KiB).
+const npyMaxHeader = 1 << 16
+
+var (
+	npyMagic   = []byte("\x93NUMPY")
+	npyDescr   = regexp.MustCompile(`'descr'\s*:\s*'([^']*)'`)
+	npyFortran = regexp.MustCompile(`'fortran_order'\s*:\s*(True|False)`)
+	npyShape   = regexp.MustCompile(`'shape'\s*:\s*\(([^)]*)\)`)
+)
+
+func readNPY(r io.Reader) (values []complex128, err error) {
+
+	var preamble [8]byte
+	if _, err = io.ReadFull(r, preamble[:]); err != nil {
+		return nil, fmt.Errorf("io.ReadFull: %w", err)
+	}
+
+	if !bytes.Equal(preamble[:6], npyMagic) {
+		return nil, fmt.Errorf("not a NumPy .npy file")
+	}
+
+	var headerLen uint32
+	switch major := preamble[6]; major {
+	case 1:
+		var l uint16
+		err = binary.Read(r, binary.LittleEndian, &l)
+		headerLen = uint32(l)
+	case 2, 3:
+		err = binary.Read(r, binary.LittleEndian, &headerLen)
+	default:
+		return nil, fmt.Errorf("unsupported .npy version %d", major)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("binary.Read: %w", err)
+	}
+
+	if headerLen > npyMaxHeader {
+		return nil, fmt.Errorf("header of %d bytes, more than %d", headerLen, npyMaxHeader)
+	}
+
+	header := make([]byte, headerLen)
+	if _, err = io.ReadFull(r, header); err != nil {
+		return nil, fmt.Errorf("io.ReadFull: %w", err)
+	}
+
+	descr := npyDescr.FindSubmatch(header)
+	fortran := npyFortran.FindSubmatch(header)
+	shape := npyShape.FindSubmatch(header)
+	if descr == nil || fortran == nil || shape == nil {
+		return nil, fmt.Errorf("invalid header %q", header)
+	}
+
+	n, ndim := 1, 0
+	for _, dim := range strings.Split(string(shape[1]), ",") {
+		if dim = strings.TrimSpace(dim); dim == "" {
+			continue
+		}
+		var d int
+		if d, err = strconv.Atoi(dim); err != nil {
+			return nil, fmt.Errorf("invalid shape (%s): %w", shape[1], err)
+		}
+		if d < 0 {
+			return nil, fmt.Errorf("invalid shape (%s): negative dimension %d", shape[1], d)
+		}
+		if d != 0 && n > MaxValues/d {
+			return nil, fmt.Errorf("invalid shape (%s): more than %d values", shape[1], MaxValues)
+		}
+		n *= d
+		ndim++
+	}
+
+	if string(fortran[1]) == "True" && ndim > 1 {
+		return nil, fmt.Errorf("arrays in Fortran order are not supported")
+	}
+
+	var order binary.ByteOrder = binary.LittleEndian
+	dtype := string(descr[1])
+	if dtype == "" {
+		return nil, fmt.Errorf("invalid header %q: empty descr", header)
+	}
+
+	switch dtype[0] {
+	case '<', '|', '=':
+		dtype = dtype[1:]
+	case '>':
+		order, dtype = binary.BigEndian, dtype[1:]
+	}
+
+	var read func() (complex128, error)
+	switch dtype {
+	case "f8":
+		read = func() (complex128, error) {
+			var x float64
+			err := binary.Read(r, order, &x)
+			return complex(x, 0), err
+		}
+	case "f4":
+		read = func() (complex128, error) {
+			var x float32
+			err := binary.Read(r, order, &x)
+			return complex(float64(x), 0), err
+		}
+	case "i8":
+		read = func() (complex128, error) {
+			var x int64
+			err := binary.Read(r, order, &x)
+			return complex(float64(x), 0), err
+		}
+	case "i4":
+		read = func() (complex128, error) {
+			var x int32
+			err := binary.Read(r, order, &x)
+			return complex(float64(x), 0), err
+		}
+	case "c16":
+		read = func() (complex128, error) {
+			var x [2]float64
+			err := binary.Read(r, order, &x)
+			return complex(x[0], x[1]), err
+		}
+	default:
+		return nil, fmt.Errorf("unsupported dtype %q, supported dtypes are [f4 f8 i4 i8 c16]", descr[1])
+	}
+
+	// Grows with the values actually read, in case the file is shorter than its shape
+	values = make([]complex128, 0, min(n, 1<<16))
+	for i := 0; i < n; i++ {
+		var v complex128
+		if v, err = read(); err != nil {
+			return nil, fmt.Errorf("value %d: binary.Read: %w", i, err)
+		}
+		if math.IsNaN(real(v)) || math.IsNaN(imag(v)) {
+			return nil, fmt.Errorf("value %d is NaN", i)
+		}
+		values = append(values, v)
+	}
+
+	return
+}
@@ -0,0 +1,62 @@
+package challenge
+
+import (
+	"encoding/binary"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// npy returns a version 1.0 .npy file with the given header and float64 values.
+func npy(header string, values ...float64) []byte {
+	data := append([]byte{}, npyMagic...)
+	data = append(data, 1, 0)
+	data = binary.LittleEndian.AppendUint16(data, uint16(len(header)))
+	data = append(data, header...)
+	for _, v := range values {
+		data = binary.LittleEndian.AppendUint64(data, math.Float64bits(v))
+	}
+	return data
+}
+
+func TestReadValuesNPY(t *testing.T) {
+
+	for _, tc := range []struct {
+		name string
+		data []byte
+		err  string // Expected error, empty if valid.
+	}{
+		{"Valid", npy("{'descr': '<f8', 'fortran_order': False, 'shape': (3,), }", 1, 2, 3), ""},
+		{"EmptyDescr", npy("{'descr': '', 'fortran_order': False, 'shape': (3,), }", 1, 2, 3), "empty descr"},
+		{"NegativeShape", npy("{'descr': '<f8', 'fortran_order': False, 'shape': (-3,), }"), "negative dimension"},
+		{"LargeShape", npy("{'descr': '<f8', 'fortran_order': False, 'shape': (65536, 65536, 65536), }"), "more than"},
+		{"LargeHeader", append(append([]byte{}, npyMagic...), 2, 0, 0xff, 0xff, 0xff, 0xff), "header of 4294967295 bytes"},
+		{"Truncated", npy("{'descr': '<f8', 'fortran_order': False, 'shape': (1000000,), }", 1, 2), "value 2"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+
+			path := filepath.Join(t.TempDir(), "values.npy")
+			if err := os.WriteFile(path, tc.data, 0600); err != nil {
+				t.Fatal(err)
+			}
+
+			values, err := ReadValues(path)
+
+			if tc.err == "" {
+				if err != nil {
+					t.Fatal(err)
+				}
+				if len(values) != 3 || values[2] != 3 {
+					t.Fatalf("values=%v, want [1 2 3]", values)
+				}
+				return
+			}
+
+			if err == nil || !strings.Contains(err.Error(), tc.err) {
+				t.Fatalf("err=%v, want an error containing %q", err, tc.err)
+			}
+		})
+	}
+}
@@ -0,0 +1,84 @@
+package challenge
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// DefaultTestcases is the number of testcases of a suite when config.json does not specify it.
+const DefaultTestcases = 8
+
+// SuiteLiteral is the "Suite" block of config.json, read by suite.go.
+type SuiteLiteral struct {
+	Testcases int                              // Number of testcases, default: DefaultTestcases
+	Inputs    []map[string]DistributionLiteral `json:",omitempty"` // Distributions of the inputs, taken in turn by the testcases, default: the "Inputs" of the "Challenge" block
+}
+
+// SuiteLiteralFromJSON returns the "Suite" block of config.json with its defaults set.
+func SuiteLiteralFromJSON(data []byte) (lit SuiteLiteral, err error) {
+
+	aux := struct {
+		Suite SuiteLiteral
+	}{}
+
+	if err = json.Unmarshal(data, &aux); err != nil {
+		return
+	}
+
+	lit = aux.Suite
+
+	if lit.Testcases == 0 {
+		lit.Testcases = DefaultTestcases
+	}
+
+	if lit.Testcases < 0 {
+		return lit, fmt.Errorf("invalid Suite.Testcases=%d", lit.Testcases)
+	}
+
+	return
+}
+
+// Testcase returns the literal of the i-th testcase of the suite: its seed is
+// lit.Seed+i, and so are the seeds of the inputs that have one, and its inputs
+// follow the i-th distributions of Inputs, cyclically.
+func (s SuiteLiteral) Testcase(lit Literal, i int) (tc Literal, err error) {
+
+	if i < 0 || i >= s.Testcases {
+		return tc, fmt.Errorf("invalid testcase %d, the suite has %d testcases", i, s.Testcases)
+	}
+
+	tc = lit
+	tc.Seed += int64(i)
+
+	inputs := lit.Inputs
+	if len(s.Inputs) != 0 {
+		inputs = s.Inputs[i%len(s.Inputs)]
+	}
+
+	tc.Inputs = make(map[string]DistributionLiteral, len(inputs))
+	for name, d := range inputs {
+		if d.Seed != nil {
+			seed := *d.Seed + int64(i)
+			d.Seed = &seed
+		}
+		tc.Inputs[name] = d
+	}
+
+	return
+}
+
+// TestcaseFromJSON returns the "Challenge" block of config.json for the i-th
+// testcase of the suite of its "Suite" block, or as is if i is negative.
+func TestcaseFromJSON(data []byte, i int) (lit Literal, err error) {
+
+	if lit, err = LiteralFromJSON(data); err != nil || i < 0 {
+		return
+	}
+
+	var s SuiteLiteral
+	if s, err = SuiteLiteralFromJSON(data); err != nil {
+		return
+	}
+
+	return s.Testcase(lit, i)
+}
@@ -0,0 +1,138 @@
+// Package cleartext implements, on vectors of slots, the operations of the
+// homomorphic evaluator. It is used to shadow an encrypted computation.
+package cleartext
+
+import (
+	"math"
+	"math/big"
+	"math/cmplx"
+
+	"github.com/tuneinsight/lattigo/v5/utils/bignum"
+)
+
+// FromOperand returns the slots of a scalar or vector operand of the
+// homomorphic evaluator, padded or truncated to n slots.
+// It returns false for any other operand (ciphertexts, plaintexts).
+func FromOperand(op interface{}, n int) (v []complex128, ok bool) {
+
+	v = make([]complex128, n)
+
+	switch op := op.(type) {
+	case complex128:
+		fill(v, op)
+	case float64:
+		fill(v, complex(op, 0))
+	case int:
+		fill(v, complex(float64(op), 0))
+	case int64:
+		fill(v, complex(float64(op), 0))
+	case uint:
+		fill(v, complex(float64(op), 0))
+	case uint64:
+		fill(v, complex(float64(op), 0))
+	case *big.Int:
+		f, _ := new(big.Float).SetInt(op).Float64()
+		fill(v, complex(f, 0))
+	case *big.Float:
+		f, _ := op.Float64()
+		fill(v, complex(f, 0))
+	case *bignum.Complex:
+		fill(v, op.Complex128())
+	case []complex128:
+		copy(v, op)
+	case []float64:
+		for i := 0; i < len(op) && i < n; i++ {
+			v[i] = complex(op[i], 0)
+		}
+	case []*big.Float:
+		for i := 0; i < len(op) && i < n; i++ {
+			if op[i] != nil {
+				f, _ := op[i].Float64()
+				v[i] = complex(f, 0)
+			}
+		}
+	case []*bignum.Complex:
+		for i := 0; i < len(op) && i < n; i++ {
+			if op[i] != nil {
+				v[i] = op[i].Complex128()
+			}
+		}
+	default:
+		return nil, false
+	}
+
+	return v, true
+}
+
+func fill(v []complex128, x complex128) {
+	for i := range v {
+		v[i] = x
+	}
+}
+
+// Add returns a + b.
+func Add(a, b []complex128) (c []complex128) {
+	c = make([]complex128, len(a))
+	for i := range c {
+		c[i] = a[i] + b[i]
+	}
+	return
+}
+
+// Sub returns a - b.
+func Sub(a, b []complex128) (c []complex128) {
+	c = make([]complex128, len(a))
+	for i := range c {
+		c[i] = a[i] - b[i]
+	}
+	return
+}
+
+// Mul returns the slot-wise product of a and b.
+func Mul(a, b []complex128) (c []complex128) {
+	c = make([]complex128, len(a))
+	for i := range c {
+		c[i] = a[i] * b[i]
+	}
+	return
+}
+
+// Rotate returns a cyclically rotated by k positions to the left.
+func Rotate(a []complex128, k int) (c []complex128) {
+	n := len(a)
+	c = make([]complex128, n)
+	if n == 0 {
+		return
+	}
+	k = ((k % n) + n) % n
+	copy(c, a[k:])
+	copy(c[n-k:], a[:k])
+	return
+}
+
+// Conjugate returns the complex conjugate of a.
+func Conjugate(a []complex128) (c []complex128) {
+	c = make([]complex128, len(a))
+	for i := range c {
+		c[i] = cmplx.Conj(a[i])
+	}
+	return
+}
+
+// MaxAbs returns the largest modulus among the slots of a.
+func MaxAbs(a []complex128) (max float64) {
+	for i := range a {
+		max = math.Max(max, cmplx.Abs(a[i]))
+	}
+	return
+}
+
+// Precision returns the minimum, over the slots, of the L2 precision
+// in bits of have with respect to want.
+func Precision(have, want []complex128) float64 {
+	var delta float64
+	for i := range want {
+		delta = math.Max(delta, cmplx.Abs(have[i]-want[i]))
+	}
+	return -math.Log2(delta)
+}
@@ -0,0 +1,283 @@
+// Package debug implements an evaluator that decrypts, with the secret key,
+// the result of every homomorphic operation and compares it against a
+// cleartext shadow of the computation. It is enabled with main.go --debug-sk.
+package debug
+
+import (
+	"fmt"
+	"log"
+	"math"
+
+	"github.com/tuneinsight/lattigo/v5/core/rlwe"
+	"github.com/tuneinsight/lattigo/v5/he/hefloat"
+
+	"app/internal/cleartext"
+	"app/utils"
+)
+
+// Evaluator wraps a hefloat.Evaluator. After each operation, it decrypts the
+// result and logs its level, scale, largest slot magnitude and precision with
+// respect to the same operation evaluated on the cleartext shadows of the operands.
+// The shadow of a ciphertext not produced by the Evaluator, or modified outside
+// of it (e.g. by a bootstrapping or a linear transformation, which use the
+// wrapped hefloat.Evaluator directly), is its decryption, which is logged as
+// the precision reported then only covers the following operations.
+type Evaluator struct {
+	*hefloat.Evaluator
+	ecd    *hefloat.Encoder
+	dec    *rlwe.Decryptor
+	logger *log.Logger
+
+	shadows *shadows // Shared with the shallow copies.
+}
+
+var _ utils.Evaluator = (*Evaluator)(nil)
+var _ utils.ShallowCopier = (*Evaluator)(nil)
+
+// NewEvaluator returns a new debug Evaluator wrapping eval and logging to logger.
+func NewEvaluator(params hefloat.Parameters, eval *hefloat.Evaluator, sk *rlwe.SecretKey, logger *log.Logger) *Evaluator {
+	return &Evaluator{
+		Evaluator: eval,
+		ecd:       hefloat.NewEncoder(params),
+		dec:       rlwe.NewDecryptor(params, sk),
+		logger:    logger,
+		shadows:   newShadows(),
+	}
+}
+
+// ShallowCopy returns a copy of the Evaluator that can be used concurrently,
+// sharing its shadows and its logger.
+func (eval *Evaluator) ShallowCopy() (utils.Evaluator, error) {
+	return &Evaluator{
+		Evaluator: eval.Evaluator.ShallowCopy(),
+		ecd:       eval.ecd.ShallowCopy(),
+		dec:       eval.dec.ShallowCopy(),
+		logger:    eval.logger,
+		shadows:   eval.shadows,
+	}, nil
+}
+
+// Decrypt returns the decrypted slots of ct.
+func (eval *Evaluator) Decrypt(ct *rlwe.Ciphertext) (values []complex128) {
+	values = make([]complex128, ct.Slots())
+	if err := eval.ecd.Decode(eval.dec.DecryptNew(ct), values); err != nil {
+		// Sanity check, this error should not happen.
+		panic(err)
+	}
+	return
+}
+
+// Track sets the cleartext shadow of ct. At most MaxShadows shadows are
+// kept, the least recently used being evicted.
+func (eval *Evaluator) Track(ct *rlwe.Ciphertext, values []complex128) {
+	eval.shadows.set(ct, append([]complex128{}, values...))
+}
+
+// shadow returns the cleartext shadow of a ciphertext, plaintext, scalar or vector
+// operand of the operation name.
+func (eval *Evaluator) shadow(name string, op interface{}, slots int) []complex128 {
+
+	switch op := op.(type) {
+	case *rlwe.Ciphertext:
+		v, found, modified := eval.shadows.get(op)
+		switch {
+		case found:
+			return v
+		case modified:
+			eval.logger.Printf("%-16s operand modified outside the evaluator (bootstrapping, linear transformation), its shadow is reset to its decryption", name)
+		default:
+			eval.logger.Printf("%-16s operand without shadow (produced outside the evaluator or evicted), its shadow is its decryption", name)
+		}
+		return eval.Decrypt(op)
+	case *rlwe.Plaintext:
+		v := make([]complex128, op.Slots())
+		if err := eval.ecd.Decode(op, v); err != nil {
+			panic(err)
+		}
+		return v
+	}
+
+	if v, ok := cleartext.FromOperand(op, slots); ok {
+		return v
+	}
+
+	panic(fmt.Errorf("debug: unsupported operand type %T", op))
+}
+
+// check runs the operation, stores the shadow of its result and
+// logs the comparison with the decrypted result.
+func (eval *Evaluator) check(name string, want []complex128, run func() (*rlwe.Ciphertext, error)) error {
+
+	ct, err := run()
+	if err != nil {
+		eval.logger.Printf("%-16s error: %s", name, err.Error())
+		return err
+	}
+
+	eval.Track(ct, want)
+
+	have := eval.Decrypt(ct)
+
+	eval.logger.Printf("%-16s level=%2d logscale=%6.2f max|slot|=%.4g prec=%5.2f bits",
+		name, ct.Level(), math.Log2(ct.Scale.Float64()), cleartext.MaxAbs(have), cleartext.Precision(have, want[:len(have)]))
+
+	return nil
+}
+
+func (eval *Evaluator) binary(name string, op0 *rlwe.Ciphertext, op1 interface{}, f func(a, b []complex128) []complex128, run func() (*rlwe.Ciphertext, error)) error {
+	a := eval.shadow(name, op0, op0.Slots())
+	b := eval.shadow(name, op1, op0.Slots())
+	return eval.check(name, f(a, b[:len(a)]), run)
+}
+
+func (eval *Evaluator) unary(name string, op0 *rlwe.Ciphertext, f func(a []complex128) []complex128, run func() (*rlwe.Ciphertext, error)) error {
+	return eval.check(name, f(eval.shadow(name, op0, op0.Slots())), run)
+}
+
+func (eval *Evaluator) accumulate(name string, op0 *rlwe.Ciphertext, op1 interface{}, opOut *rlwe.Ciphertext, run func() (*rlwe.Ciphertext, error)) error {
+	a := eval.shadow(name, op0, op0.Slots())
+	b := eval.shadow(name, op1, op0.Slots())
+	c := eval.shadow(name, opOut, op0.Slots())
+	return eval.check(name, cleartext.Add(c[:len(a)], cleartext.Mul(a, b[:len(a)])), run)
+}
+
+func identity(a []complex128) []complex128 {
+	return a
+}
+
+func rotate(k int) func(a []complex128) []complex128 {
+	return func(a []complex128) []complex128 {
+		return cleartext.Rotate(a, k)
+	}
+}
+
+func (eval *Evaluator) Add(op0 *rlwe.Ciphertext, op1 rlwe.Operand, opOut *rlwe.Ciphertext) (err error) {
+	return eval.binary("Add", op0, op1, cleartext.Add, func() (*rlwe.Ciphertext, error) {
+		return opOut, eval.Evaluator.Add(op0, op1, opOut)
+	})
+}
+
+func (eval *Evaluator) AddNew(op0 *rlwe.Ciphertext, op1 rlwe.Operand) (opOut *rlwe.Ciphertext, err error) {
+	err = eval.binary("AddNew", op0, op1, cleartext.Add, func() (*rlwe.Ciphertext, error) {
+		opOut, err = eval.Evaluator.AddNew(op0, op1)
+		return opOut, err
+	})
+	return
+}
+
+func (eval *Evaluator) Sub(op0 *rlwe.Ciphertext, op1 rlwe.Operand, opOut *rlwe.Ciphertext) (err error) {
+	return eval.binary("Sub", op0, op1, cleartext.Sub, func() (*rlwe.Ciphertext, error) {
+		return opOut, eval.Evaluator.Sub(op0, op1, opOut)
+	})
+}
+
+func (eval *Evaluator) SubNew(op0 *rlwe.Ciphertext, op1 rlwe.Operand) (opOut *rlwe.Ciphertext, err error) {
+	err = eval.binary("SubNew", op0, op1, cleartext.Sub, func() (*rlwe.Ciphertext, error) {
+		opOut, err = eval.Evaluator.SubNew(op0, op1)
+		return opOut, err
+	})
+	return
+}
+
+func (eval *Evaluator) Mul(op0 *rlwe.Ciphertext, op1 rlwe.Operand, opOut *rlwe.Ciphertext) (err error) {
+	return eval.binary("Mul", op0, op1, cleartext.Mul, func() (*rlwe.Ciphertext, error) {
+		return opOut, eval.Evaluator.Mul(op0, op1, opOut)
+	})
+}
+
+func (eval *Evaluator) MulNew(op0 *rlwe.Ciphertext, op1 rlwe.Operand) (opOut *rlwe.Ciphertext, err error) {
+	err = eval.binary("MulNew", op0, op1, cleartext.Mul, func() (*rlwe.Ciphertext, error) {
+		opOut, err = eval.Evaluator.MulNew(op0, op1)
+		return opOut, err
+	})
+	return
+}
+
+func (eval *Evaluator) MulRelin(op0 *rlwe.Ciphertext, op1 rlwe.Operand, opOut *rlwe.Ciphertext) (err error) {
+	return eval.binary("MulRelin", op0, op1, cleartext.Mul, func() (*rlwe.Ciphertext, error) {
+		return opOut, eval.Evaluator.MulRelin(op0, op1, opOut)
+	})
+}
+
+func (eval *Evaluator) MulRelinNew(op0 *rlwe.Ciphertext, op1 rlwe.Operand) (opOut *rlwe.Ciphertext, err error) {
+	err = eval.binary("MulRelinNew", op0, op1, cleartext.Mul, func() (*rlwe.Ciphertext, error) {
+		opOut, err = eval.Evaluator.MulRelinNew(op0, op1)
+		return opOut, err
+	})
+	return
+}
+
+func (eval *Evaluator) MulThenAdd(op0 *rlwe.Ciphertext, op1 rlwe.Operand, opOut *rlwe.Ciphertext) (err error) {
+	return eval.accumulate("MulThenAdd", op0, op1, opOut, func() (*rlwe.Ciphertext, error) {
+		return opOut, eval.Evaluator.MulThenAdd(op0, op1, opOut)
+	})
+}
+
+func (eval *Evaluator) MulRelinThenAdd(op0 *rlwe.Ciphertext, op1 rlwe.Operand, opOut *rlwe.Ciphertext) (err error) {
+	return eval.accumulate("MulRelinThenAdd", op0, op1, opOut, func() (*rlwe.Ciphertext, error) {
+		return opOut, eval.Evaluator.MulRelinThenAdd(op0, op1, opOut)
+	})
+}
+
+func (eval *Evaluator) Relinearize(op0, opOut *rlwe.Ciphertext) (err error) {
+	return eval.unary("Relinearize", op0, identity, func() (*rlwe.Ciphertext, error) {
+		return opOut, eval.Evaluator.Relinearize(op0, opOut)
+	})
+}
+
+func (eval *Evaluator) RelinearizeNew(op0 *rlwe.Ciphertext) (opOut *rlwe.Ciphertext, err error) {
+	err = eval.unary("RelinearizeNew", op0, identity, func() (*rlwe.Ciphertext, error) {
+		opOut, err = eval.Evaluator.RelinearizeNew(op0)
+		return opOut, err
+	})
+	return
+}
+
+func (eval *Evaluator) Rescale(op0, opOut *rlwe.Ciphertext) (err error) {
+	return eval.unary("Rescale", op0, identity, func() (*rlwe.Ciphertext, error) {
+		return opOut, eval.Evaluator.Rescale(op0, opOut)
+	})
+}
+
+func (eval *Evaluator) Rotate(op0 *rlwe.Ciphertext, k int, opOut *rlwe.Ciphertext) (err error) {
+	return eval.unary(fmt.Sprintf("Rotate(%d)", k), op0, rotate(k), func() (*rlwe.Ciphertext, error) {
+		return opOut, eval.Evaluator.Rotate(op0, k, opOut)
+	})
+}
+
+func (eval *Evaluator) RotateNew(op0 *rlwe.Ciphertext, k int) (opOut *rlwe.Ciphertext, err error) {
+	err = eval.unary(fmt.Sprintf("RotateNew(%d)", k), op0, rotate(k), func() (*rlwe.Ciphertext, error) {
+		opOut, err = eval.Evaluator.RotateNew(op0, k)
+		return opOut, err
+	})
+	return
+}
+
+func (eval *Evaluator) Conjugate(op0, opOut *rlwe.Ciphertext) (err error) {
+	return eval.unary("Conjugate", op0, cleartext.Conjugate, func() (*rlwe.Ciphertext, error) {
+		return opOut, eval.Evaluator.Conjugate(op0, opOut)
+	})
+}
+
+func (eval *Evaluator) ConjugateNew(op0 *rlwe.Ciphertext) (opOut *rlwe.Ciphertext, err error) {
+	err = eval.unary("ConjugateNew", op0, cleartext.Conjugate, func() (*rlwe.Ciphertext, error) {
+		opOut, err = eval.Evaluator.ConjugateNew(op0)
+		return opOut, err
+	})
+	return
+}
+
+func (eval *Evaluator) DropLevel(op0 *rlwe.Ciphertext, levels int) {
+	_ = eval.unary("DropLevel", op0, identity, func() (*rlwe.Ciphertext, error) {
+		eval.Evaluator.DropLevel(op0, levels)
+		return op0, nil
+	})
+}
+
+func (eval *Evaluator) DropLevelNew(op0 *rlwe.Ciphertext, levels int) (opOut *rlwe.Ciphertext) {
+	_ = eval.unary("DropLevelNew", op0, identity, func() (*rlwe.Ciphertext, error) {
+		opOut = eval.Evaluator.DropLevelNew(op0, levels)
+		return opOut, nil
+	})
+	return
+}
@@ -0,0 +1,109 @@
+package debug
+
+import (
+	"container/list"
+	"encoding/binary"
+	"hash/fnv"
+	"math"
+	"sync"
+
+	"github.com/tuneinsight/lattigo/v5/core/rlwe"
+)
+
+// MaxShadows is the maximum number of cleartext shadows kept by an Evaluator
+// and its shallow copies. The least recently used shadow is evicted first.
+const MaxShadows = 256
+
+// shadows stores the cleartext shadows of the ciphertexts, shared by an
+// Evaluator and its shallow copies.
+type shadows struct {
+	mu      sync.Mutex
+	entries map[*rlwe.Ciphertext]*list.Element
+	lru     *list.List // Front: the most recently used shadow.
+}
+
+type entry struct {
+	ct          *rlwe.Ciphertext
+	values      []complex128
+	fingerprint uint64
+}
+
+func newShadows() *shadows {
+	return &shadows{entries: map[*rlwe.Ciphertext]*list.Element{}, lru: list.New()}
+}
+
+// set stores the shadow of ct, evicting the least recently used shadow
+// beyond MaxShadows.
+func (s *shadows) set(ct *rlwe.Ciphertext, values []complex128) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	e := &entry{ct: ct, values: values, fingerprint: fingerprint(ct)}
+
+	if el, ok := s.entries[ct]; ok {
+		el.Value = e
+		s.lru.MoveToFront(el)
+		return
+	}
+
+	s.entries[ct] = s.lru.PushFront(e)
+
+	if s.lru.Len() > MaxShadows {
+		el := s.lru.Back()
+		delete(s.entries, el.Value.(*entry).ct)
+		s.lru.Remove(el)
+	}
+}
+
+// get returns the shadow of ct. found is false if ct has no shadow (an input
+// not tracked, a shadow evicted or a ciphertext produced outside the Evaluator),
+// and modified is true if ct was modified outside the Evaluator since its
+// shadow was set, in which case the shadow is dropped.
+func (s *shadows) get(ct *rlwe.Ciphertext) (values []complex128, found, modified bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	el, ok := s.entries[ct]
+	if !ok {
+		return nil, false, false
+	}
+
+	e := el.Value.(*entry)
+	if e.fingerprint != fingerprint(ct) {
+		delete(s.entries, ct)
+		s.lru.Remove(el)
+		return nil, false, true
+	}
+
+	s.lru.MoveToFront(el)
+
+	return e.values, true, false
+}
+
+// fingerprint hashes the metadata and the first coefficients of ct,
+// which change with any operation on ct.
+func fingerprint(ct *rlwe.Ciphertext) uint64 {
+	h := fnv.New64a()
+	var buf [8]byte
+
+	write := func(x uint64) {
+		binary.LittleEndian.PutUint64(buf[:], x)
+		h.Write(buf[:])
+	}
+
+	write(uint64(ct.Level()))
+	write(uint64(ct.Degree()))
+	write(math.Float64bits(ct.Scale.Float64()))
+
+	for _, p := range ct.Value {
+		if len(p.Coeffs) == 0 {
+			continue
+		}
+		coeffs := p.Coeffs[0]
+		for _, c := range coeffs[:min(len(coeffs), 16)] {
+			write(c)
+		}
+	}
+
+	return h.Sum64()
+}
@@ -0,0 +1,260 @@
+// Package grader grades submissions as the platform does, for grader.go: it
+// unpacks the archive of package.go in a sandbox folder, generates fresh keys
+// and inputs with a hidden seed, runs the solution with time and memory limits
+// and verifies its output.
+//
+// The secret key and the config.json holding the hidden seed are only on disk
+// while setup.go and verify.go run, in a temporary folder outside of that of
+// the submission, so that a solution cannot read them. The sandbox is otherwise
+// a folder, not an isolation: the solution runs with the rights of the grader.
+package grader
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"app/internal/report"
+)
+
+// Grader grades the submissions one at a time, so that their runtimes are comparable.
+type Grader struct {
+	Dir    string // Folder of the submissions, each graded in Dir/<ID>.
+	Setup  string // Executable of setup.go, built from the tree of the grader.
+	Verify string // Executable of verify.go, built from the tree of the grader.
+	Config []byte // config.json of the platform, see Config.
+	Seed   int64  // Hidden seed of the inputs.
+
+	Timeout   time.Duration // Time limit of the solution, none if 0.
+	MaxMemory uint64        // Memory limit of the solution in bytes, none if 0.
+	MaxSize   int64         // Maximum uncompressed size of a submission in bytes.
+	Keep      bool          // Keeps the public keys, the inputs and the sources of the submissions.
+
+	grading sync.Mutex // Held for the whole grading of a submission.
+	mu      sync.Mutex // Guards results, held briefly so that Results does not wait for a grading.
+	results []Result
+}
+
+// Result is the result of a submission.
+type Result struct {
+	ID        int
+	Name      string `json:",omitempty"`
+	Submitted time.Time
+	Passed    bool
+	Precision float64        // Minimum L2 precision in bits, 0 if the submission did not run to completion.
+	Runtime   time.Duration  `json:",omitempty"` // Wall time of main.go measured by the grader, in nanoseconds.
+	Memory    uint64         `json:",omitempty"` // Peak resident memory of main.go in bytes.
+	Error     string         `json:",omitempty"` // Error of the step that did not complete, if any.
+	Log       string         // File with the output of the steps.
+	Report    *report.Report `json:",omitempty"`
+}
+
+// Grade grades the submission archive under the given name, and records its result.
+func (g *Grader) Grade(name string, archive []byte) (res Result) {
+
+	g.grading.Lock()
+	defer g.grading.Unlock()
+
+	// The gradings are serialized, so the number of results is a unique ID
+	g.mu.Lock()
+	res = Result{ID: len(g.results), Name: name, Submitted: time.Now()}
+	g.mu.Unlock()
+
+	defer func() {
+		g.mu.Lock()
+		g.results = append(g.results, res)
+		g.mu.Unlock()
+	}()
+
+	dir, err := filepath.Abs(filepath.Join(g.Dir, strconv.Itoa(res.ID)))
+	if err != nil {
+		res.Error = err.Error()
+		return
+	}
+
+	// A folder left by a previous run of the grader is replaced
+	if err = os.RemoveAll(dir); err != nil {
+		res.Error = err.Error()
+		return
+	}
+
+	// The solution runs in app/. The secret key and the config.json holding
+	// the hidden seed are in a private folder outside of dir, which only
+	// exists while setup and verify run
+	app := filepath.Join(dir, "app")
+	res.Log = filepath.Join(dir, "log.txt")
+
+	if err = os.MkdirAll(app, 0700); err != nil {
+		res.Error = err.Error()
+		return
+	}
+
+	if !g.Keep {
+		defer func() {
+			if err := os.RemoveAll(app); err != nil && res.Error == "" {
+				res.Error = err.Error()
+			}
+		}()
+	}
+
+	if err = Unzip(archive, app, g.MaxSize); err != nil {
+		res.Error = "unzip: " + err.Error()
+		return
+	}
+
+	submitted, err := os.ReadFile(filepath.Join(app, "config.json"))
+	if err != nil {
+		res.Error = "config: " + err.Error()
+		return
+	}
+
+	config, err := Config(submitted, g.Config, g.Seed)
+	if err != nil {
+		res.Error = "config: " + err.Error()
+		return
+	}
+
+	main := filepath.Join(dir, "main")
+	if _, err = run(app, res.Log, 0, 0, "go", "build", "-o", main, "main.go"); err != nil {
+		res.Error = "build: " + err.Error()
+		return
+	}
+
+	if !g.Keep {
+		defer os.Remove(main)
+	}
+
+	temps := filepath.Join(app, "temps")
+	if err = os.MkdirAll(temps, 0700); err != nil {
+		res.Error = err.Error()
+		return
+	}
+
+	cc, evk := filepath.Join(temps, "cc.bin"), filepath.Join(temps, "evalkey.bin")
+	in, out := filepath.Join(temps, "in.bin"), filepath.Join(temps, "out.bin")
+
+	private, err := g.private(map[string][]byte{"config.json": config})
+	if err != nil {
+		res.Error = "setup: " + err.Error()
+		return
+	}
+
+	if _, err = run(private, res.Log, 0, 0, g.Setup, "--sk=sk.bin", "--cc="+cc, "--key_eval="+evk, "--input="+in); err != nil {
+		os.RemoveAll(private)
+		res.Error = "setup: " + err.Error()
+		return
+	}
+
+	// The secret key is held in memory while the solution runs
+	sk, err := os.ReadFile(filepath.Join(private, "sk.bin"))
+	if rerr := os.RemoveAll(private); err == nil {
+		err = rerr
+	}
+
+	if err != nil {
+		res.Error = "setup: " + err.Error()
+		return
+	}
+
+	usage, err := run(app, res.Log, g.Timeout, g.MaxMemory, main, "--cc="+cc, "--key_eval="+evk, "--input="+in, "--output="+out)
+	res.Runtime, res.Memory = usage.Runtime, usage.Memory
+	if err != nil {
+		res.Error = "solution: " + err.Error()
+		return
+	}
+
+	// The runtime measured by the grader replaces the one of main.go
+	if private, err = g.private(map[string][]byte{
+		"config.json": config,
+		"sk.bin":      sk,
+		"runtime.txt": []byte(usage.Runtime.String()),
+	}); err != nil {
+		res.Error = "verify: " + err.Error()
+		return
+	}
+
+	defer os.RemoveAll(private)
+
+	reportFile := filepath.Join(private, "report.json")
+
+	// verify exits with an error if the testcase fails, after writing its report
+	_, err = run(private, res.Log, 0, 0, g.Verify, "--sk=sk.bin", "--cc="+cc, "--output="+out, "--runtime=runtime.txt", "--report=report.json")
+
+	rep, rerr := report.ReadFile(reportFile)
+	if rerr != nil {
+		if err == nil {
+			err = rerr
+		}
+		res.Error = "verify: " + err.Error()
+		return
+	}
+
+	res.Report = &rep
+	res.Passed = rep.Passed
+	res.Precision = rep.Precision.Min.L2
+
+	return
+}
+
+// private returns a new private folder, outside of Dir, holding the given
+// files. The caller removes it.
+func (g *Grader) private(files map[string][]byte) (dir string, err error) {
+
+	if dir, err = os.MkdirTemp("", "grader-"); err != nil {
+		return "", fmt.Errorf("os.MkdirTemp: %w", err)
+	}
+
+	for name, data := range files {
+		if err = os.WriteFile(filepath.Join(dir, name), data, 0600); err != nil {
+			os.RemoveAll(dir)
+			return "", fmt.Errorf("os.WriteFile: %w", err)
+		}
+	}
+
+	return
+}
+
+// Results returns the results of the submissions, the passed ones first, by
+// increasing runtime, then the failed ones by decreasing precision.
+func (g *Grader) Results() []Result {
+
+	g.mu.Lock()
+	results := append([]Result{}, g.results...)
+	g.mu.Unlock()
+
+	sort.SliceStable(results, func(i, j int) bool {
+		a, b := results[i], results[j]
+		switch {
+		case a.Passed != b.Passed:
+			return a.Passed
+		case a.Passed:
+			return a.Runtime < b.Runtime
+		default:
+			return a.Precision > b.Precision
+		}
+	})
+
+	return results
+}
+
+// String returns a one-line summary of the result.
+func (r Result) String() string {
+
+	name := strconv.Itoa(r.ID)
+	if r.Name != "" {
+		name += " (" + r.Name + ")"
+	}
+
+	switch {
+	case r.Error != "":
+		return fmt.Sprintf("submission %s: ERROR (%s)", name, r.Error)
+	case r.Passed:
+		return fmt.Sprintf("submission %s: PASS (min precision %.2f bits, runtime %s, memory %d MiB)", name, r.Precision, r.Runtime, r.Memory>>20)
+	default:
+		return fmt.Sprintf("submission %s: FAIL (min precision %.2f bits, runtime %s, memory %d MiB)", name, r.Precision, r.Runtime, r.Memory>>20)
+	}
+}
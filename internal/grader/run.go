@@ -0,0 +1,135 @@
+package grader
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Usage is the resources used by a command.
+type Usage struct {
+	Runtime time.Duration // Wall time.
+	Memory  uint64        // Peak resident memory in bytes, sampled every SamplingPeriod, 0 if unavailable.
+}
+
+// SamplingPeriod is the period at which the resident memory of a command is sampled.
+var SamplingPeriod = 50 * time.Millisecond
+
+// run runs the command in dir, appends its output to logFile, and kills it
+// after timeout, if positive, or when its resident memory exceeds maxMemory
+// bytes, if positive. The error includes the last line of the output.
+func run(dir, logFile string, timeout time.Duration, maxMemory uint64, name string, args ...string) (u Usage, err error) {
+
+	ctx, cancel := context.WithCancelCause(context.Background())
+	defer cancel(nil)
+
+	if timeout > 0 {
+		var cancelTimeout context.CancelFunc
+		ctx, cancelTimeout = context.WithTimeoutCause(ctx, timeout, fmt.Errorf("time limit of %s exceeded", timeout))
+		defer cancelTimeout()
+	}
+
+	var out bytes.Buffer
+
+	/* #nosec G204 */
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.Dir = dir
+	cmd.Stdout, cmd.Stderr = &out, &out
+
+	now := time.Now()
+
+	if err = cmd.Start(); err != nil {
+		return
+	}
+
+	done := make(chan struct{})
+	sampled := make(chan uint64)
+
+	go func() {
+
+		var peak uint64
+
+		ticker := time.NewTicker(SamplingPeriod)
+		defer ticker.Stop()
+
+		for {
+			if rss, ok := residentMemory(cmd.Process.Pid); ok && rss > peak {
+				peak = rss
+				if maxMemory > 0 && rss > maxMemory {
+					cancel(fmt.Errorf("memory limit of %d MiB exceeded", maxMemory>>20))
+				}
+			}
+
+			select {
+			case <-done:
+				sampled <- peak
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+
+	err = cmd.Wait()
+	u.Runtime = time.Since(now)
+
+	close(done)
+	u.Memory = <-sampled
+
+	if ferr := appendLog(logFile, dir, name, args, out.Bytes()); ferr != nil && err == nil {
+		err = ferr
+	}
+
+	if cause := context.Cause(ctx); cause != nil {
+		return u, cause
+	}
+
+	if err != nil {
+		var exitErr *exec.ExitError
+		if lines := strings.Split(strings.TrimSpace(out.String()), "\n"); errors.As(err, &exitErr) && lines[len(lines)-1] != "" {
+			return u, fmt.Errorf("%w: %s", err, lines[len(lines)-1])
+		}
+	}
+
+	return
+}
+
+// residentMemory returns the resident memory of the process pid, read from
+// /proc on Linux, and false elsewhere.
+func residentMemory(pid int) (uint64, bool) {
+
+	data, err := os.ReadFile("/proc/" + strconv.Itoa(pid) + "/statm")
+	if err != nil {
+		return 0, false
+	}
+
+	fields := strings.Fields(string(data))
+	if len(fields) < 2 {
+		return 0, false
+	}
+
+	pages, err := strconv.ParseUint(fields[1], 10, 64)
+	if err != nil {
+		return 0, false
+	}
+
+	return pages * uint64(os.Getpagesize()), true
+}
+
+// appendLog appends the command and its output to logFile.
+func appendLog(logFile, dir, name string, args []string, out []byte) error {
+
+	f, err := os.OpenFile(logFile, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	_, err = fmt.Fprintf(f, "$ (cd %s && %s %s)\n%s\n", dir, name, strings.Join(args, " "), out)
+	return err
+}
@@ -0,0 +1,170 @@
+package grader
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// Unzip extracts the archive data to dir. If the files of the archive are in a
+// single folder, as the archive of package.go in app/, the folder is stripped.
+// The archive must contain a go.mod, and its files must not exceed maxSize bytes
+// uncompressed.
+func Unzip(data []byte, dir string, maxSize int64) (err error) {
+
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return fmt.Errorf("zip.NewReader: %w", err)
+	}
+
+	// The folder holding go.mod is the root of the module
+	root := "."
+	var found bool
+	for _, f := range zr.File {
+		if name := path.Clean(f.Name); path.Base(name) == "go.mod" && (!found || len(name) < len(path.Join(root, "go.mod"))) {
+			root, found = path.Dir(name), true
+		}
+	}
+
+	if !found {
+		return fmt.Errorf("the archive has no go.mod")
+	}
+
+	var size int64
+	for _, f := range zr.File {
+
+		name := path.Clean(f.Name)
+
+		if root != "." {
+			if !strings.HasPrefix(name, root+"/") {
+				continue
+			}
+			name = strings.TrimPrefix(name, root+"/")
+		}
+
+		if path.IsAbs(name) || name == ".." || strings.HasPrefix(name, "../") {
+			return fmt.Errorf("invalid path %q", f.Name)
+		}
+
+		if f.FileInfo().IsDir() {
+			continue
+		}
+
+		if !f.Mode().IsRegular() {
+			return fmt.Errorf("%s is not a regular file", f.Name)
+		}
+
+		if size += int64(f.UncompressedSize64); size > maxSize {
+			return fmt.Errorf("the archive exceeds %d bytes uncompressed", maxSize)
+		}
+
+		if err = extract(f, filepath.Join(dir, filepath.FromSlash(name)), maxSize); err != nil {
+			return fmt.Errorf("%s: %w", f.Name, err)
+		}
+	}
+
+	return
+}
+
+// extract writes the file f to dst, reading at most maxSize bytes, which
+// guards against an uncompressed size understated in the archive.
+func extract(f *zip.File, dst string, maxSize int64) (err error) {
+
+	if err = os.MkdirAll(filepath.Dir(dst), 0700); err != nil {
+		return
+	}
+
+	rc, err := f.Open()
+	if err != nil {
+		return
+	}
+	defer rc.Close()
+
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0600)
+	if err != nil {
+		return
+	}
+
+	n, err := io.Copy(out, io.LimitReader(rc, maxSize+1))
+	if cerr := out.Close(); err == nil {
+		err = cerr
+	}
+
+	if err == nil && n > maxSize {
+		err = fmt.Errorf("exceeds %d bytes uncompressed", maxSize)
+	}
+
+	return
+}
+
+// Blocks are the blocks of config.json set by the platform, not by the submission.
+var Blocks = []string{"Challenge", "Security"}
+
+// Config returns the config.json of the submission with the Blocks of the
+// config.json of the platform and the seed of the challenge replaced by seed,
+// so that the inputs cannot be known in advance.
+func Config(submission, platform []byte, seed int64) ([]byte, error) {
+
+	var sub, plat map[string]json.RawMessage
+
+	if err := json.Unmarshal(submission, &sub); err != nil {
+		return nil, fmt.Errorf("config.json of the submission: json.Unmarshal: %w", err)
+	}
+
+	if err := json.Unmarshal(platform, &plat); err != nil {
+		return nil, fmt.Errorf("config.json of the platform: json.Unmarshal: %w", err)
+	}
+
+	for _, block := range Blocks {
+
+		// encoding/json matches the keys case-insensitively
+		for k := range sub {
+			if strings.EqualFold(k, block) {
+				delete(sub, k)
+			}
+		}
+
+		if plat[block] != nil {
+			sub[block] = plat[block]
+		}
+	}
+
+	var challenge map[string]json.RawMessage
+	if sub["Challenge"] != nil {
+		if err := json.Unmarshal(sub["Challenge"], &challenge); err != nil {
+			return nil, fmt.Errorf("Challenge: json.Unmarshal: %w", err)
+		}
+	}
+
+	if challenge == nil {
+		challenge = map[string]json.RawMessage{}
+	}
+
+	for k := range challenge {
+		if strings.EqualFold(k, "Seed") {
+			delete(challenge, k)
+		}
+	}
+
+	challenge["Seed"], _ = json.Marshal(seed)
+
+	var err error
+	if sub["Challenge"], err = json.Marshal(challenge); err != nil {
+		return nil, fmt.Errorf("json.Marshal: %w", err)
+	}
+
+	// The testcase is the "Challenge" block as is
+	for k := range sub {
+		if strings.EqualFold(k, "Suite") {
+			delete(sub, k)
+		}
+	}
+
+	return json.MarshalIndent(sub, "", "    ")
+}
@@ -0,0 +1,252 @@
+package grader
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"hash/crc32"
+	"io/fs"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// file is a file of an archive built by archive.
+type file struct {
+	name string
+	data string
+	mode fs.FileMode // Regular file if 0.
+	size uint64      // Declared uncompressed size, if not 0.
+}
+
+// archive returns a zip archive of the files.
+func archive(t *testing.T, files ...file) []byte {
+
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+
+	for _, f := range files {
+
+		h := &zip.FileHeader{Name: f.name, Method: zip.Store}
+		if f.mode != 0 {
+			h.SetMode(f.mode)
+		}
+
+		if f.size == 0 {
+			w, err := zw.CreateHeader(h)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err = w.Write([]byte(f.data)); err != nil {
+				t.Fatal(err)
+			}
+			continue
+		}
+
+		// Understates the uncompressed size
+		h.CRC32 = crc32.ChecksumIEEE([]byte(f.data))
+		h.CompressedSize64, h.UncompressedSize64 = uint64(len(f.data)), f.size
+		w, err := zw.CreateRaw(h)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err = w.Write([]byte(f.data)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	return buf.Bytes()
+}
+
+// files returns the files under dir, relative to dir.
+func files(t *testing.T, dir string) (names []string) {
+
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		rel, err := filepath.Rel(dir, path)
+		names = append(names, filepath.ToSlash(rel))
+		return err
+	})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sort.Strings(names)
+
+	return
+}
+
+func TestUnzip(t *testing.T) {
+
+	const maxSize = 1 << 10
+
+	for _, tc := range []struct {
+		name  string
+		files []file
+		want  []string // Files extracted, relative to the folder of the submission.
+		err   string   // Expected error, empty if valid.
+	}{
+		{
+			name:  "Root",
+			files: []file{{name: "app/go.mod"}, {name: "app/main.go"}, {name: "app/internal/solution/solution.go"}},
+			want:  []string{"go.mod", "internal/solution/solution.go", "main.go"},
+		},
+		{
+			name:  "NoRoot",
+			files: []file{{name: "go.mod"}, {name: "main.go"}},
+			want:  []string{"go.mod", "main.go"},
+		},
+		{
+			name:  "OutsideRoot",
+			files: []file{{name: "app/go.mod"}, {name: "other/main.go"}, {name: "app/../../evil"}},
+			want:  []string{"go.mod"},
+		},
+		{
+			name:  "NoGoMod",
+			files: []file{{name: "app/main.go"}},
+			err:   "no go.mod",
+		},
+		{
+			name:  "DotDot",
+			files: []file{{name: "go.mod"}, {name: "../evil"}},
+			err:   "evil",
+		},
+		{
+			name:  "Absolute",
+			files: []file{{name: "go.mod"}, {name: "/tmp/evil"}},
+			err:   "evil",
+		},
+		{
+			name:  "Symlink",
+			files: []file{{name: "go.mod"}, {name: "link", data: "/etc/passwd", mode: fs.ModeSymlink | 0777}},
+			err:   "not a regular file",
+		},
+		{
+			name:  "DeclaredSize",
+			files: []file{{name: "go.mod", data: strings.Repeat("x", maxSize/2)}, {name: "main.go", data: strings.Repeat("x", maxSize/2+1)}},
+			err:   "exceeds",
+		},
+		{
+			name:  "ActualSize",
+			files: []file{{name: "go.mod"}, {name: "main.go", data: strings.Repeat("x", 2*maxSize), size: 1}},
+			err:   "main.go",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+
+			// The submission is extracted in a sub-folder, so that files
+			// written outside of it are found
+			root := t.TempDir()
+			dir := filepath.Join(root, "app")
+
+			err := Unzip(archive(t, tc.files...), dir, maxSize)
+
+			if tc.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.err) {
+					t.Fatalf("err=%v, want an error containing %q", err, tc.err)
+				}
+			} else if err != nil {
+				t.Fatal(err)
+			}
+
+			for _, name := range files(t, root) {
+				if !strings.HasPrefix(name, "app/") {
+					t.Fatalf("%s written outside of the folder of the submission", name)
+				}
+			}
+
+			if tc.err == "" {
+				if got := files(t, dir); strings.Join(got, " ") != strings.Join(tc.want, " ") {
+					t.Fatalf("extracted %v, want %v", got, tc.want)
+				}
+			}
+		})
+	}
+}
+
+func TestConfig(t *testing.T) {
+
+	const seed = 42
+
+	for _, tc := range []struct {
+		name                 string
+		submission, platform string
+		want                 map[string]string // Expected blocks, compacted, nil if invalid.
+	}{
+		{
+			name:       "Override",
+			submission: `{"Scheme": {"LogN": 14}, "Challenge": {"Name": "own", "Seed": 1}, "Security": {"MinBits": 0}, "Suite": {"Testcases": 2}}`,
+			platform:   `{"Challenge": {"Name": "parity"}, "Security": {"MinBits": 128}}`,
+			want: map[string]string{
+				"Scheme":    `{"LogN":14}`,
+				"Challenge": `{"Name":"parity","Seed":42}`,
+				"Security":  `{"MinBits":128}`,
+			},
+		},
+		{
+			name:       "CaseInsensitive",
+			submission: `{"challenge": {"Name": "own"}, "SECURITY": {"MinBits": 0}, "suite": {}}`,
+			platform:   `{"Challenge": {"Name": "parity", "seed": 7}, "Security": {"MinBits": 128}}`,
+			want: map[string]string{
+				"Challenge": `{"Name":"parity","Seed":42}`,
+				"Security":  `{"MinBits":128}`,
+			},
+		},
+		{
+			name:       "NoPlatformBlock",
+			submission: `{"Challenge": {"Name": "own", "Seed": 1}, "Security": {"MinBits": 0}}`,
+			platform:   `{}`,
+			want: map[string]string{
+				"Challenge": `{"Seed":42}`,
+			},
+		},
+		{
+			name:       "Invalid",
+			submission: `{"Challenge": `,
+			platform:   `{}`,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+
+			data, err := Config([]byte(tc.submission), []byte(tc.platform), seed)
+
+			if tc.want == nil {
+				if err == nil {
+					t.Fatalf("invalid config.json accepted")
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var got map[string]json.RawMessage
+			if err = json.Unmarshal(data, &got); err != nil {
+				t.Fatal(err)
+			}
+
+			if len(got) != len(tc.want) {
+				t.Fatalf("blocks %s, want %v", data, tc.want)
+			}
+
+			for k, want := range tc.want {
+				var buf bytes.Buffer
+				if err = json.Compact(&buf, got[k]); err != nil {
+					t.Fatalf("%s: %v", k, err)
+				}
+				if buf.String() != want {
+					t.Fatalf("%s=%s, want %s", k, buf.String(), want)
+				}
+			}
+		})
+	}
+}
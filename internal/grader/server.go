@@ -0,0 +1,69 @@
+package grader
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+)
+
+// Handler returns the HTTP handler of the grader:
+//
+//	POST /grade?name=<name>  grades the zip archive of the body and returns its Result
+//	GET  /results            returns the Results, best first
+//
+// The archive of a submission is at most maxArchive bytes.
+func (g *Grader) Handler(maxArchive int64, logger *log.Logger) http.Handler {
+
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("/grade", func(w http.ResponseWriter, r *http.Request) {
+
+		if r.Method != http.MethodPost {
+			http.Error(w, "POST the zip archive of the submission, see package.go", http.StatusMethodNotAllowed)
+			return
+		}
+
+		archive, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxArchive))
+		if err != nil {
+			http.Error(w, fmt.Sprintf("reading the archive: %s", err), http.StatusRequestEntityTooLarge)
+			return
+		}
+
+		name := r.URL.Query().Get("name")
+		logger.Printf("grading %q (%d bytes) from %s", name, len(archive), r.RemoteAddr)
+
+		res := g.Grade(name, archive)
+		logger.Println(res)
+
+		writeJSON(w, res, logger)
+	})
+
+	mux.HandleFunc("/results", func(w http.ResponseWriter, r *http.Request) {
+
+		if r.Method != http.MethodGet {
+			http.Error(w, "GET the results", http.StatusMethodNotAllowed)
+			return
+		}
+
+		writeJSON(w, g.Results(), logger)
+	})
+
+	return mux
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}, logger *log.Logger) {
+
+	data, err := json.MarshalIndent(v, "", "    ")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	if _, err = w.Write(append(data, '\n')); err != nil {
+		logger.Printf("http.ResponseWriter.Write: %s", err)
+	}
+}
@@ -0,0 +1,72 @@
+package profile
+
+import (
+	"context"
+
+	"github.com/tuneinsight/lattigo/v5/core/rlwe"
+	"github.com/tuneinsight/lattigo/v5/he"
+
+	"app/utils"
+)
+
+// Bootstrapper wraps a he.Bootstrapper[rlwe.Ciphertext] and records the
+// bootstrappings as spans of the Profile of the Evaluator wrapping it, nested
+// in the spans of the Evaluator. Once the context is done, the bootstrappings
+// return the error of the context without being evaluated.
+type Bootstrapper struct {
+	he.Bootstrapper[rlwe.Ciphertext]
+	ctx context.Context
+	p   *Profile
+	t   *track
+}
+
+var _ he.Bootstrapper[rlwe.Ciphertext] = (*Bootstrapper)(nil)
+var _ utils.BootstrapperWrapper = (*Evaluator)(nil)
+var _ utils.BootstrapperShallowCopier = (*Bootstrapper)(nil)
+
+// WrapBootstrapper returns btp wrapped in a Bootstrapper sharing the context,
+// the Profile and the track of the Evaluator, see utils.WrapBootstrapper.
+func (eval *Evaluator) WrapBootstrapper(btp he.Bootstrapper[rlwe.Ciphertext]) he.Bootstrapper[rlwe.Ciphertext] {
+	return &Bootstrapper{Bootstrapper: btp, ctx: eval.ctx, p: eval.p, t: eval.t}
+}
+
+// ShallowCopy returns a copy of the Bootstrapper, wrapping a shallow copy of
+// the wrapped bootstrapper, that can be used concurrently. Its spans are
+// nested in the innermost span of the Bootstrapper at their opening.
+func (btp *Bootstrapper) ShallowCopy() (he.Bootstrapper[rlwe.Ciphertext], error) {
+
+	inner, err := utils.ShallowCopyBootstrapper(btp.Bootstrapper)
+	if err != nil {
+		return nil, err
+	}
+
+	b := &Bootstrapper{Bootstrapper: inner, ctx: btp.ctx, p: btp.p}
+	if btp.p != nil {
+		b.t = btp.p.newTrack(btp.t)
+	}
+
+	return b, nil
+}
+
+// Unwrap returns the wrapped bootstrapper.
+func (btp *Bootstrapper) Unwrap() he.Bootstrapper[rlwe.Ciphertext] {
+	return btp.Bootstrapper
+}
+
+func (btp *Bootstrapper) Bootstrap(ct *rlwe.Ciphertext) (*rlwe.Ciphertext, error) {
+	end, err := begin(btp.ctx, btp.p, btp.t, "Bootstrap")
+	if err != nil {
+		return nil, err
+	}
+	defer end()
+	return btp.Bootstrapper.Bootstrap(ct)
+}
+
+func (btp *Bootstrapper) BootstrapMany(cts []rlwe.Ciphertext) ([]rlwe.Ciphertext, error) {
+	end, err := begin(btp.ctx, btp.p, btp.t, "BootstrapMany")
+	if err != nil {
+		return nil, err
+	}
+	defer end()
+	return btp.Bootstrapper.BootstrapMany(cts)
+}
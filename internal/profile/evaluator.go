@@ -0,0 +1,242 @@
+package profile
+
+import (
+	"context"
+
+	"github.com/tuneinsight/lattigo/v5/core/rlwe"
+
+	"app/utils"
+)
+
+// Evaluator wraps a utils.Evaluator and records every operation as a span of
+// its Profile, named after the method, nested in the spans opened with
+// utils.Begin on the Evaluator. Once its context is done, the operations
+// returning an error return the error of the context without being evaluated,
+// which stops the solutions that check the errors.
+type Evaluator struct {
+	utils.Evaluator
+	ctx context.Context
+	p   *Profile
+	t   *track
+}
+
+var _ utils.Evaluator = (*Evaluator)(nil)
+var _ utils.Profiler = (*Evaluator)(nil)
+var _ utils.ShallowCopier = (*Evaluator)(nil)
+
+// NewEvaluator returns a new Evaluator wrapping eval, cancelled with ctx and
+// recording to p, which can be nil to only check ctx.
+func NewEvaluator(ctx context.Context, eval utils.Evaluator, p *Profile) *Evaluator {
+	e := &Evaluator{Evaluator: eval, ctx: ctx, p: p}
+	if p != nil {
+		e.t = p.root
+	}
+	return e
+}
+
+// ShallowCopy returns a copy of the Evaluator, wrapping a shallow copy of the
+// wrapped evaluator, that can be used concurrently. Its spans are nested in
+// the innermost span of the Evaluator at their opening.
+func (eval *Evaluator) ShallowCopy() (utils.Evaluator, error) {
+
+	inner, err := utils.ShallowCopy(eval.Evaluator)
+	if err != nil {
+		return nil, err
+	}
+
+	e := &Evaluator{Evaluator: inner, ctx: eval.ctx, p: eval.p}
+	if eval.p != nil {
+		e.t = eval.p.newTrack(eval.t)
+	}
+
+	return e, nil
+}
+
+// Begin opens the span name in the Profile, see utils.Begin.
+func (eval *Evaluator) Begin(name string) (end func()) {
+	return eval.p.begin(eval.t, name)
+}
+
+// Unwrap returns the wrapped evaluator, to which the operations that are not
+// part of utils.Evaluator, such as the linear transformations, are delegated.
+func (eval *Evaluator) Unwrap() utils.Evaluator {
+	return eval.Evaluator
+}
+
+// begin returns the error of the context if it is done, and opens the span name otherwise.
+func (eval *Evaluator) begin(name string) (end func(), err error) {
+	return begin(eval.ctx, eval.p, eval.t, name)
+}
+
+// begin returns the error of ctx if it is done, and opens the span name of p on t otherwise.
+func begin(ctx context.Context, p *Profile, t *track, name string) (end func(), err error) {
+	if err = ctx.Err(); err != nil {
+		return nil, err
+	}
+	return p.begin(t, name), nil
+}
+
+func (eval *Evaluator) Add(op0 *rlwe.Ciphertext, op1 rlwe.Operand, opOut *rlwe.Ciphertext) (err error) {
+	end, err := eval.begin("Add")
+	if err != nil {
+		return
+	}
+	defer end()
+	return eval.Evaluator.Add(op0, op1, opOut)
+}
+
+func (eval *Evaluator) AddNew(op0 *rlwe.Ciphertext, op1 rlwe.Operand) (opOut *rlwe.Ciphertext, err error) {
+	end, err := eval.begin("AddNew")
+	if err != nil {
+		return
+	}
+	defer end()
+	return eval.Evaluator.AddNew(op0, op1)
+}
+
+func (eval *Evaluator) Sub(op0 *rlwe.Ciphertext, op1 rlwe.Operand, opOut *rlwe.Ciphertext) (err error) {
+	end, err := eval.begin("Sub")
+	if err != nil {
+		return
+	}
+	defer end()
+	return eval.Evaluator.Sub(op0, op1, opOut)
+}
+
+func (eval *Evaluator) SubNew(op0 *rlwe.Ciphertext, op1 rlwe.Operand) (opOut *rlwe.Ciphertext, err error) {
+	end, err := eval.begin("SubNew")
+	if err != nil {
+		return
+	}
+	defer end()
+	return eval.Evaluator.SubNew(op0, op1)
+}
+
+func (eval *Evaluator) Mul(op0 *rlwe.Ciphertext, op1 rlwe.Operand, opOut *rlwe.Ciphertext) (err error) {
+	end, err := eval.begin("Mul")
+	if err != nil {
+		return
+	}
+	defer end()
+	return eval.Evaluator.Mul(op0, op1, opOut)
+}
+
+func (eval *Evaluator) MulNew(op0 *rlwe.Ciphertext, op1 rlwe.Operand) (opOut *rlwe.Ciphertext, err error) {
+	end, err := eval.begin("MulNew")
+	if err != nil {
+		return
+	}
+	defer end()
+	return eval.Evaluator.MulNew(op0, op1)
+}
+
+func (eval *Evaluator) MulRelin(op0 *rlwe.Ciphertext, op1 rlwe.Operand, opOut *rlwe.Ciphertext) (err error) {
+	end, err := eval.begin("MulRelin")
+	if err != nil {
+		return
+	}
+	defer end()
+	return eval.Evaluator.MulRelin(op0, op1, opOut)
+}
+
+func (eval *Evaluator) MulRelinNew(op0 *rlwe.Ciphertext, op1 rlwe.Operand) (opOut *rlwe.Ciphertext, err error) {
+	end, err := eval.begin("MulRelinNew")
+	if err != nil {
+		return
+	}
+	defer end()
+	return eval.Evaluator.MulRelinNew(op0, op1)
+}
+
+func (eval *Evaluator) MulThenAdd(op0 *rlwe.Ciphertext, op1 rlwe.Operand, opOut *rlwe.Ciphertext) (err error) {
+	end, err := eval.begin("MulThenAdd")
+	if err != nil {
+		return
+	}
+	defer end()
+	return eval.Evaluator.MulThenAdd(op0, op1, opOut)
+}
+
+func (eval *Evaluator) MulRelinThenAdd(op0 *rlwe.Ciphertext, op1 rlwe.Operand, opOut *rlwe.Ciphertext) (err error) {
+	end, err := eval.begin("MulRelinThenAdd")
+	if err != nil {
+		return
+	}
+	defer end()
+	return eval.Evaluator.MulRelinThenAdd(op0, op1, opOut)
+}
+
+func (eval *Evaluator) Relinearize(op0, opOut *rlwe.Ciphertext) (err error) {
+	end, err := eval.begin("Relinearize")
+	if err != nil {
+		return
+	}
+	defer end()
+	return eval.Evaluator.Relinearize(op0, opOut)
+}
+
+func (eval *Evaluator) RelinearizeNew(op0 *rlwe.Ciphertext) (opOut *rlwe.Ciphertext, err error) {
+	end, err := eval.begin("RelinearizeNew")
+	if err != nil {
+		return
+	}
+	defer end()
+	return eval.Evaluator.RelinearizeNew(op0)
+}
+
+func (eval *Evaluator) Rescale(op0, opOut *rlwe.Ciphertext) (err error) {
+	end, err := eval.begin("Rescale")
+	if err != nil {
+		return
+	}
+	defer end()
+	return eval.Evaluator.Rescale(op0, opOut)
+}
+
+func (eval *Evaluator) Rotate(op0 *rlwe.Ciphertext, k int, opOut *rlwe.Ciphertext) (err error) {
+	end, err := eval.begin("Rotate")
+	if err != nil {
+		return
+	}
+	defer end()
+	return eval.Evaluator.Rotate(op0, k, opOut)
+}
+
+func (eval *Evaluator) RotateNew(op0 *rlwe.Ciphertext, k int) (opOut *rlwe.Ciphertext, err error) {
+	end, err := eval.begin("RotateNew")
+	if err != nil {
+		return
+	}
+	defer end()
+	return eval.Evaluator.RotateNew(op0, k)
+}
+
+func (eval *Evaluator) Conjugate(op0, opOut *rlwe.Ciphertext) (err error) {
+	end, err := eval.begin("Conjugate")
+	if err != nil {
+		return
+	}
+	defer end()
+	return eval.Evaluator.Conjugate(op0, opOut)
+}
+
+func (eval *Evaluator) ConjugateNew(op0 *rlwe.Ciphertext) (opOut *rlwe.Ciphertext, err error) {
+	end, err := eval.begin("ConjugateNew")
+	if err != nil {
+		return
+	}
+	defer end()
+	return eval.Evaluator.ConjugateNew(op0)
+}
+
+// DropLevel cannot return the error of the context, it is always evaluated.
+func (eval *Evaluator) DropLevel(op0 *rlwe.Ciphertext, levels int) {
+	defer eval.p.begin(eval.t, "DropLevel")()
+	eval.Evaluator.DropLevel(op0, levels)
+}
+
+// DropLevelNew cannot return the error of the context, it is always evaluated.
+func (eval *Evaluator) DropLevelNew(op0 *rlwe.Ciphertext, levels int) (opOut *rlwe.Ciphertext) {
+	defer eval.p.begin(eval.t, "DropLevelNew")()
+	return eval.Evaluator.DropLevelNew(op0, levels)
+}
@@ -0,0 +1,301 @@
+// Package profile records the wall time, the allocations and the number of
+// calls of the phases of main.go, of the spans opened with utils.Begin and of
+// every operation of the evaluator given to SolveTestcase. It is enabled with
+// main.go --profile, which writes the profile in the folded format of the
+// flame graphs and prints a summary table, partial if the time limit of
+// main.go --timeout is reached. Its Evaluator also checks that time limit.
+package profile
+
+import (
+	"fmt"
+	"io"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Stat is the cost of a span.
+type Stat struct {
+	Calls  int
+	Time   time.Duration // Wall time, nested spans included.
+	Bytes  uint64        // Bytes allocated by the process, nested spans included.
+	Allocs uint64        // Heap objects allocated by the process, nested spans included.
+}
+
+func (s *Stat) add(t Stat) {
+	s.Calls += t.Calls
+	s.Time += t.Time
+	s.Bytes += t.Bytes
+	s.Allocs += t.Allocs
+}
+
+func (s *Stat) sub(t Stat) {
+	// The spans of the shallow copies of an Evaluator, evaluated concurrently,
+	// can cost more than the span they are nested in, which then costs nothing
+	s.Time -= min(s.Time, t.Time)
+	s.Bytes -= min(s.Bytes, t.Bytes)
+	s.Allocs -= min(s.Allocs, t.Allocs)
+}
+
+// Profile records the cost of nested spans, by path of span names. The spans
+// of a track must be closed in the reverse order of their opening, which holds
+// as long as each track is used by a single goroutine: Begin uses the root
+// track, and each shallow copy of an Evaluator its own. The allocations are
+// those of the whole process.
+type Profile struct {
+	mu     sync.Mutex
+	root   *track
+	tracks []*track
+	stats  map[string]*Stat // By path, the names of the nested spans separated by semicolons.
+}
+
+// track is a stack of spans, whose first span is nested in the innermost
+// span of its parent, if any, at its opening.
+type track struct {
+	parent *track
+	stack  []span // Spans opened and not closed yet, innermost last.
+}
+
+// path returns the path of the innermost open span of the track, or of its parent.
+func (t *track) path() string {
+	for ; t != nil; t = t.parent {
+		if n := len(t.stack); n > 0 {
+			return t.stack[n-1].path
+		}
+	}
+	return ""
+}
+
+// span is an open span.
+type span struct {
+	path          string
+	start         time.Time
+	bytes, allocs uint64 // runtime.MemStats.TotalAlloc and Mallocs at its opening.
+}
+
+// New returns an empty Profile.
+func New() *Profile {
+	p := &Profile{stats: map[string]*Stat{}}
+	p.root = p.newTrack(nil)
+	return p
+}
+
+// newTrack returns a new track nested in parent.
+func (p *Profile) newTrack(parent *track) *track {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	t := &track{parent: parent}
+	p.tracks = append(p.tracks, t)
+	return t
+}
+
+// Begin opens the span name, nested in the spans opened and not closed yet,
+// and returns the function closing it. It does nothing on a nil Profile.
+func (p *Profile) Begin(name string) (end func()) {
+	if p == nil {
+		return func() {}
+	}
+	return p.begin(p.root, name)
+}
+
+// begin opens the span name on the track t.
+func (p *Profile) begin(t *track, name string) (end func()) {
+
+	if p == nil {
+		return func() {}
+	}
+
+	// The separator of the folded format cannot appear in a name
+	name = strings.ReplaceAll(name, ";", ",")
+
+	var m0, m1 runtime.MemStats
+	runtime.ReadMemStats(&m0)
+
+	s := span{path: name, bytes: m0.TotalAlloc, allocs: m0.Mallocs}
+
+	p.mu.Lock()
+	if parent := t.path(); parent != "" {
+		s.path = parent + ";" + name
+	}
+	s.start = time.Now()
+	t.stack = append(t.stack, s)
+	p.mu.Unlock()
+
+	path, now := s.path, s.start
+
+	return func() {
+
+		elapsed := time.Since(now)
+		runtime.ReadMemStats(&m1)
+
+		p.mu.Lock()
+		defer p.mu.Unlock()
+
+		t.stack = t.stack[:len(t.stack)-1]
+
+		if p.stats[path] == nil {
+			p.stats[path] = &Stat{}
+		}
+
+		p.stats[path].add(Stat{
+			Calls:  1,
+			Time:   elapsed,
+			Bytes:  m1.TotalAlloc - m0.TotalAlloc,
+			Allocs: m1.Mallocs - m0.Mallocs,
+		})
+	}
+}
+
+// paths returns the sorted paths of the spans, their costs, and the costs
+// excluding the nested spans. The spans still open, e.g. interrupted by a
+// timeout, count up to now.
+func (p *Profile) paths() (paths []string, total, self map[string]Stat) {
+
+	var m runtime.MemStats
+	runtime.ReadMemStats(&m)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	total = map[string]Stat{}
+	for path, s := range p.stats {
+		total[path] = *s
+	}
+
+	for _, t := range p.tracks {
+		for _, s := range t.stack {
+			st := total[s.path]
+			st.add(Stat{Calls: 1, Time: time.Since(s.start), Bytes: m.TotalAlloc - s.bytes, Allocs: m.Mallocs - s.allocs})
+			total[s.path] = st
+		}
+	}
+
+	self = map[string]Stat{}
+	for path, s := range total {
+		paths = append(paths, path)
+		self[path] = s
+	}
+
+	for path, s := range total {
+		if i := strings.LastIndexByte(path, ';'); i >= 0 {
+			if parent, ok := self[path[:i]]; ok {
+				parent.sub(s)
+				self[path[:i]] = parent
+			}
+		}
+	}
+
+	sort.Strings(paths)
+
+	return
+}
+
+// WriteFolded writes the profile in the folded format read by flamegraph.pl,
+// speedscope or inferno: one line per path of spans, followed by the wall time
+// spent in the last span, nested spans excluded, in microseconds.
+func (p *Profile) WriteFolded(w io.Writer) (err error) {
+
+	paths, _, self := p.paths()
+
+	for _, path := range paths {
+		if us := self[path].Time.Microseconds(); us > 0 {
+			if _, err = fmt.Fprintf(w, "%s %d\n", path, us); err != nil {
+				return
+			}
+		}
+	}
+
+	return
+}
+
+// Summary returns the costs of the spans aggregated by name, sorted by
+// decreasing wall time. The wall time of a span nested in a span of the same
+// name is only counted once, and the percentage is relative to the wall time
+// of the outermost spans.
+func (p *Profile) Summary() string {
+
+	paths, total, self := p.paths()
+
+	byName := map[string]*[2]Stat{} // Total and self.
+	var run time.Duration
+
+	for _, path := range paths {
+
+		names := strings.Split(path, ";")
+		name := names[len(names)-1]
+
+		if len(names) == 1 {
+			run += total[path].Time
+		}
+
+		if byName[name] == nil {
+			byName[name] = &[2]Stat{}
+		}
+
+		s := total[path]
+		for _, ancestor := range names[:len(names)-1] {
+			if ancestor == name {
+				s = Stat{Calls: s.Calls}
+				break
+			}
+		}
+
+		byName[name][0].add(s)
+		byName[name][1].add(self[path])
+	}
+
+	names := make([]string, 0, len(byName))
+	for name := range byName {
+		names = append(names, name)
+	}
+
+	sort.Slice(names, func(i, j int) bool {
+		if ti, tj := byName[names[i]][0].Time, byName[names[j]][0].Time; ti != tj {
+			return ti > tj
+		}
+		return names[i] < names[j]
+	})
+
+	var sb strings.Builder
+
+	fmt.Fprintf(&sb, "%-24s %8s %12s %12s %12s %7s %12s %10s\n", "SPAN", "CALLS", "TOTAL", "SELF", "MEAN", "%", "ALLOC", "ALLOCS")
+
+	for _, name := range names {
+
+		s, own := byName[name][0], byName[name][1]
+
+		percent := 0.0
+		if run > 0 {
+			percent = 100 * float64(s.Time) / float64(run)
+		}
+
+		fmt.Fprintf(&sb, "%-24s %8d %12s %12s %12s %6.1f%% %12s %10d\n",
+			name,
+			s.Calls,
+			s.Time.Round(time.Microsecond),
+			own.Time.Round(time.Microsecond),
+			(s.Time / time.Duration(max(s.Calls, 1))).Round(time.Microsecond),
+			percent,
+			bytes(s.Bytes),
+			s.Allocs)
+	}
+
+	fmt.Fprintf(&sb, "total %s", run.Round(time.Microsecond))
+
+	return sb.String()
+}
+
+func bytes(n uint64) string {
+	switch {
+	case n >= 1<<30:
+		return fmt.Sprintf("%.1f GiB", float64(n)/(1<<30))
+	case n >= 1<<20:
+		return fmt.Sprintf("%.1f MiB", float64(n)/(1<<20))
+	case n >= 1<<10:
+		return fmt.Sprintf("%.1f KiB", float64(n)/(1<<10))
+	default:
+		return fmt.Sprintf("%d B", n)
+	}
+}
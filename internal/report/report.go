@@ -0,0 +1,246 @@
+// Package report computes the verdict of a testcase and its
+// machine-readable summary, written by verify.go.
+package report
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/cmplx"
+	"os"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// MaxPrecision is the precision, in bits, assigned to an exact slot.
+// It keeps the statistics finite, and thus JSON encodable.
+const MaxPrecision = 64
+
+// A slot whose error is not finite (a NaN or infinite decrypted or expected
+// value) is the worst slot: its error counts as infinite and its precision
+// as 0, the lowest precision reported.
+
+// Stats stores a precision, in bits, for the real part,
+// the imaginary part and the modulus (L2) of the error.
+type Stats struct {
+	Real, Imag, L2 float64
+}
+
+// Slot is the result of a single slot.
+type Slot struct {
+	Output    string  // Name of the output vector.
+	Index     int     // Index in the output vector.
+	Precision float64 // L2 precision in bits.
+	Have      Value   // Real and imaginary part of the decrypted value.
+	Want      Value   // Real and imaginary part of the expected value.
+}
+
+// Report is the machine-readable summary of a testcase.
+type Report struct {
+	Challenge    string
+	Passed       bool
+	MinPrecision float64 // Threshold, in bits, on Precision.Min.L2.
+
+	Precision struct {
+		Min, Avg, Median Stats
+	}
+
+	WorstSlots []Slot // Slots with the lowest L2 precision, sorted by increasing precision.
+
+	Outputs []Output
+
+	Level    int           // Lowest level of the output ciphertexts.
+	LogScale float64       // Log2 of the scale of the output ciphertext at Level.
+	Runtime  time.Duration `json:",omitempty"` // Runtime of the solution, in nanoseconds.
+}
+
+// Vector is an output vector of a testcase, decrypted and expected.
+type Vector struct {
+	Name       string
+	Have, Want []complex128
+}
+
+// Output summarizes an output vector.
+type Output struct {
+	Name         string
+	Length       int
+	MinPrecision float64 // Minimum L2 precision in bits over the values of the vector.
+}
+
+// New computes the precision statistics of the output vectors, taken
+// together, and the verdict against minPrecision. It keeps the worst slots.
+func New(name string, outputs []Vector, minPrecision float64, worst int) (r Report) {
+
+	r.Challenge = name
+	r.MinPrecision = minPrecision
+
+	var slots []Slot
+	deltas := [3][]float64{}
+
+	for _, v := range outputs {
+
+		var max float64
+
+		for i := range v.Want {
+			d := v.Have[i] - v.Want[i]
+			deltas[0] = append(deltas[0], finite(math.Abs(real(d))))
+			deltas[1] = append(deltas[1], finite(math.Abs(imag(d))))
+			deltas[2] = append(deltas[2], finite(cmplx.Abs(d)))
+			slots = append(slots, Slot{
+				Output: v.Name,
+				Index:  i,
+				Have:   Value{real(v.Have[i]), imag(v.Have[i])},
+				Want:   Value{real(v.Want[i]), imag(v.Want[i])},
+			})
+			max = math.Max(max, deltas[2][len(deltas[2])-1])
+		}
+
+		r.Outputs = append(r.Outputs, Output{Name: v.Name, Length: len(v.Want), MinPrecision: precision(max)})
+	}
+
+	n := len(slots)
+
+	var min, avg, median [3]float64
+	var worstL2 float64
+	for j := range deltas {
+		sorted := append([]float64{}, deltas[j]...)
+		sort.Float64s(sorted)
+
+		var sum float64
+		for _, d := range sorted {
+			sum += d
+		}
+
+		if n != 0 {
+			min[j] = precision(sorted[n-1])
+			avg[j] = precision(sum / float64(n))
+			median[j] = precision(sorted[n/2])
+			if j == 2 {
+				worstL2 = sorted[n-1]
+			}
+		}
+	}
+
+	r.Precision.Min = Stats{min[0], min[1], min[2]}
+	r.Precision.Avg = Stats{avg[0], avg[1], avg[2]}
+	r.Precision.Median = Stats{median[0], median[1], median[2]}
+
+	idx := make([]int, n)
+	for i := range idx {
+		idx[i] = i
+	}
+
+	sort.SliceStable(idx, func(i, j int) bool {
+		return deltas[2][idx[i]] > deltas[2][idx[j]]
+	})
+
+	if worst > n {
+		worst = n
+	}
+
+	r.WorstSlots = make([]Slot, worst)
+	for i := range r.WorstSlots {
+		k := idx[i]
+		r.WorstSlots[i] = slots[k]
+		r.WorstSlots[i].Precision = precision(deltas[2][k])
+	}
+
+	r.Passed = n != 0 && !math.IsInf(worstL2, 1) && r.Precision.Min.L2 >= minPrecision
+
+	return
+}
+
+// String returns a one-line human-readable verdict.
+func (r Report) String() string {
+	verdict := "FAIL"
+	if r.Passed {
+		verdict = "PASS"
+	}
+	return fmt.Sprintf("%s: %s (min precision %.2f bits, threshold %.2f bits)", r.Challenge, verdict, r.Precision.Min.L2, r.MinPrecision)
+}
+
+// WriteFile writes the report as indented JSON to path.
+func (r Report) WriteFile(path string) (err error) {
+	var data []byte
+	if data, err = json.MarshalIndent(r, "", "    "); err != nil {
+		return fmt.Errorf("json.MarshalIndent: %w", err)
+	}
+
+	if err = os.WriteFile(path, data, 0600); err != nil {
+		return fmt.Errorf("os.WriteFile(%s): %w", path, err)
+	}
+
+	return
+}
+
+// ReadFile reads a report written by WriteFile.
+func ReadFile(path string) (r Report, err error) {
+	var data []byte
+	/* #nosec G304 */
+	if data, err = os.ReadFile(path); err != nil {
+		return r, fmt.Errorf("os.ReadFile(%s): %w", path, err)
+	}
+
+	if err = json.Unmarshal(data, &r); err != nil {
+		return r, fmt.Errorf("json.Unmarshal: %w", err)
+	}
+
+	return
+}
+
+// finite returns delta, or +Inf if delta is NaN, so that a
+// non-finite error sorts after all the finite ones.
+func finite(delta float64) float64 {
+	if math.IsNaN(delta) {
+		return math.Inf(1)
+	}
+	return delta
+}
+
+// precision returns the precision in bits of an error, in [0, MaxPrecision].
+func precision(delta float64) float64 {
+	if math.IsNaN(delta) || math.IsInf(delta, 1) {
+		return 0
+	}
+	return math.Max(math.Min(-math.Log2(delta), MaxPrecision), 0)
+}
+
+// Value is a complex value, the real and imaginary part. Its JSON encoding
+// writes a NaN or infinite part as the string "NaN", "+Inf" or "-Inf".
+type Value [2]float64
+
+// MarshalJSON implements json.Marshaler.
+func (v Value) MarshalJSON() ([]byte, error) {
+	parts := [2]interface{}{}
+	for i, x := range v {
+		switch {
+		case math.IsNaN(x), math.IsInf(x, 0):
+			parts[i] = strconv.FormatFloat(x, 'g', -1, 64)
+		default:
+			parts[i] = x
+		}
+	}
+	return json.Marshal(parts)
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (v *Value) UnmarshalJSON(data []byte) (err error) {
+	var parts [2]json.RawMessage
+	if err = json.Unmarshal(data, &parts); err != nil {
+		return
+	}
+
+	for i, p := range parts {
+		var s string
+		if json.Unmarshal(p, &s) == nil {
+			if v[i], err = strconv.ParseFloat(s, 64); err != nil {
+				return fmt.Errorf("invalid value %q: %w", s, err)
+			}
+		} else if err = json.Unmarshal(p, &v[i]); err != nil {
+			return
+		}
+	}
+
+	return
+}
@@ -0,0 +1,64 @@
+package report
+
+import (
+	"math"
+	"path/filepath"
+	"testing"
+)
+
+func TestNewNonFinite(t *testing.T) {
+
+	want := []complex128{1, 2, 3, 4}
+
+	for _, tc := range []struct {
+		name       string
+		have, want []complex128
+	}{
+		{"NaN", []complex128{1, complex(math.NaN(), 0), 3, 4}, want},
+		{"Inf", []complex128{1, complex(math.Inf(1), 0), 3, 4}, want},
+		{"WantInf", want, []complex128{1, complex(math.Inf(-1), 0), 3, 4}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+
+			r := New("test", []Vector{{Name: "out", Have: tc.have, Want: tc.want}}, 8, 2)
+
+			if r.Passed {
+				t.Fatalf("Passed=true with a non-finite slot")
+			}
+
+			if r.Precision.Min.L2 != 0 || r.Outputs[0].MinPrecision != 0 {
+				t.Fatalf("Precision.Min.L2=%v Outputs[0].MinPrecision=%v, want 0", r.Precision.Min.L2, r.Outputs[0].MinPrecision)
+			}
+
+			if s := r.WorstSlots[0]; s.Index != 1 || s.Precision != 0 {
+				t.Fatalf("WorstSlots[0]={Index: %d, Precision: %v}, want {Index: 1, Precision: 0}", s.Index, s.Precision)
+			}
+
+			path := filepath.Join(t.TempDir(), "report.json")
+			if err := r.WriteFile(path); err != nil {
+				t.Fatal(err)
+			}
+
+			got, err := ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			have, exp := got.WorstSlots[0].Have[0], r.WorstSlots[0].Have[0]
+			if have != exp && !(math.IsNaN(have) && math.IsNaN(exp)) {
+				t.Fatalf("WorstSlots[0].Have[0]=%v after ReadFile, want %v", have, exp)
+			}
+		})
+	}
+}
+
+func TestNewExact(t *testing.T) {
+
+	v := []complex128{1, 2, 3, 4}
+
+	r := New("test", []Vector{{Name: "out", Have: v, Want: v}}, 8, 2)
+
+	if !r.Passed || r.Precision.Min.L2 != MaxPrecision {
+		t.Fatalf("Passed=%v Precision.Min.L2=%v, want true and %v", r.Passed, r.Precision.Min.L2, float64(MaxPrecision))
+	}
+}
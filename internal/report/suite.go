@@ -0,0 +1,124 @@
+package report
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"sort"
+	"time"
+)
+
+// Testcase is the result of a testcase of a suite.
+type Testcase struct {
+	Index     int
+	Seed      int64
+	Passed    bool
+	Precision float64       // Minimum L2 precision in bits, 0 if the testcase did not run to completion.
+	Runtime   time.Duration `json:",omitempty"` // Runtime of the solution, in nanoseconds.
+	Error     string        `json:",omitempty"` // Error of the step that did not complete, if any.
+}
+
+// Suite is the machine-readable summary of a suite of testcases, written by suite.go.
+type Suite struct {
+	Challenge    string
+	Passed       bool
+	PassRate     float64 // Fraction of passed testcases.
+	MinPrecision float64 // Threshold, in bits, on the precision of every testcase.
+
+	Precision struct {
+		Worst, Mean float64 // Over the minimum L2 precision of the testcases.
+	}
+
+	Runtime struct {
+		P50, P90, P99, Max time.Duration // Percentiles of the runtime of the solution, in nanoseconds.
+	}
+
+	Testcases []Testcase
+}
+
+// NewSuite aggregates the results of the testcases of a suite.
+func NewSuite(name string, minPrecision float64, testcases []Testcase) (s Suite) {
+
+	s.Challenge = name
+	s.MinPrecision = minPrecision
+	s.Testcases = testcases
+
+	if len(testcases) == 0 {
+		return
+	}
+
+	var passed int
+	var sum float64
+	var runtimes []time.Duration
+
+	s.Precision.Worst = math.Inf(1)
+
+	for _, tc := range testcases {
+
+		if tc.Passed {
+			passed++
+		}
+
+		s.Precision.Worst = math.Min(s.Precision.Worst, tc.Precision)
+		sum += tc.Precision
+
+		if tc.Runtime != 0 {
+			runtimes = append(runtimes, tc.Runtime)
+		}
+	}
+
+	s.Passed = passed == len(testcases)
+	s.PassRate = float64(passed) / float64(len(testcases))
+	s.Precision.Mean = sum / float64(len(testcases))
+
+	sort.Slice(runtimes, func(i, j int) bool { return runtimes[i] < runtimes[j] })
+
+	s.Runtime.P50 = percentile(runtimes, 50)
+	s.Runtime.P90 = percentile(runtimes, 90)
+	s.Runtime.P99 = percentile(runtimes, 99)
+	s.Runtime.Max = percentile(runtimes, 100)
+
+	return
+}
+
+// String returns a one-line human-readable verdict.
+func (s Suite) String() string {
+	verdict := "FAIL"
+	if s.Passed {
+		verdict = "PASS"
+	}
+
+	var passed int
+	for _, tc := range s.Testcases {
+		if tc.Passed {
+			passed++
+		}
+	}
+
+	return fmt.Sprintf("%s: %s (%d/%d testcases passed, worst precision %.2f bits, mean precision %.2f bits, threshold %.2f bits, runtime p50 %s p90 %s)",
+		s.Challenge, verdict, passed, len(s.Testcases), s.Precision.Worst, s.Precision.Mean, s.MinPrecision, s.Runtime.P50, s.Runtime.P90)
+}
+
+// WriteFile writes the summary as indented JSON to path.
+func (s Suite) WriteFile(path string) (err error) {
+	var data []byte
+	if data, err = json.MarshalIndent(s, "", "    "); err != nil {
+		return fmt.Errorf("json.MarshalIndent: %w", err)
+	}
+
+	if err = os.WriteFile(path, data, 0600); err != nil {
+		return fmt.Errorf("os.WriteFile(%s): %w", path, err)
+	}
+
+	return
+}
+
+// percentile returns the p-th percentile of sorted, with the nearest-rank method.
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	rank := int(math.Ceil(p / 100 * float64(len(sorted))))
+	return sorted[max(rank, 1)-1]
+}
@@ -0,0 +1,84 @@
+package simulator
+
+import (
+	"fmt"
+
+	"github.com/tuneinsight/lattigo/v5/core/rlwe"
+	"github.com/tuneinsight/lattigo/v5/he"
+	"github.com/tuneinsight/lattigo/v5/he/hefloat/bootstrapping"
+
+	"app/utils"
+)
+
+// Bootstrapper is the simulated he.Bootstrapper[rlwe.Ciphertext] of an
+// Evaluator. It passes the slots through unchanged, without the error of an
+// actual bootstrapping, and resets the level and the scale of the ciphertexts
+// as bootstrapping.Evaluator does.
+type Bootstrapper struct {
+	eval   *Evaluator
+	params bootstrapping.Parameters
+}
+
+var _ he.Bootstrapper[rlwe.Ciphertext] = (*Bootstrapper)(nil)
+var _ utils.BootstrapperShallowCopier = (*Bootstrapper)(nil)
+var _ utils.BootstrapperFactory = (*Evaluator)(nil)
+
+// NewBootstrapper returns the simulated bootstrapper of the parameters,
+// recording the bootstrappings in the trace of eval.
+func (eval *Evaluator) NewBootstrapper(params bootstrapping.Parameters) (he.Bootstrapper[rlwe.Ciphertext], error) {
+	return &Bootstrapper{eval: eval, params: params}, nil
+}
+
+// ShallowCopy returns the Bootstrapper itself, which can be used concurrently.
+func (btp *Bootstrapper) ShallowCopy() (he.Bootstrapper[rlwe.Ciphertext], error) {
+	return btp, nil
+}
+
+// Bootstrap returns a ciphertext at the output level of the bootstrapping and
+// the default scale, holding the slots of ct.
+func (btp *Bootstrapper) Bootstrap(ct *rlwe.Ciphertext) (*rlwe.Ciphertext, error) {
+
+	if ct.Degree() != 1 {
+		return nil, fmt.Errorf("cannot Bootstrap: input Ciphertext degree is %d, must be 1", ct.Degree())
+	}
+
+	if ct.Level() < btp.MinimumInputLevel() {
+		return nil, fmt.Errorf("cannot Bootstrap: input Ciphertext level is %d, below the minimum input level %d", ct.Level(), btp.MinimumInputLevel())
+	}
+
+	md := ct.MetaData.CopyNew()
+	md.Scale = btp.params.ResidualParameters.DefaultScale()
+
+	return btp.eval.output("Bootstrap", nil, md, 1, btp.OutputLevel(), btp.eval.Values(ct)), nil
+}
+
+// BootstrapMany bootstraps the ciphertexts one by one.
+func (btp *Bootstrapper) BootstrapMany(cts []rlwe.Ciphertext) ([]rlwe.Ciphertext, error) {
+
+	res := make([]rlwe.Ciphertext, len(cts))
+
+	for i := range cts {
+		ct, err := btp.Bootstrap(&cts[i])
+		if err != nil {
+			return nil, fmt.Errorf("ciphertext %d: %w", i, err)
+		}
+		res[i] = *ct
+	}
+
+	return res, nil
+}
+
+// Depth returns the number of levels consumed by the bootstrapping circuit.
+func (btp *Bootstrapper) Depth() int {
+	return btp.params.BootstrappingParameters.MaxLevel() - btp.params.ResidualParameters.MaxLevel()
+}
+
+// MinimumInputLevel returns the minimum level of a ciphertext to bootstrap.
+func (btp *Bootstrapper) MinimumInputLevel() int {
+	return btp.params.BootstrappingParameters.LevelsConsumedPerRescaling()
+}
+
+// OutputLevel returns the level of the bootstrapped ciphertexts.
+func (btp *Bootstrapper) OutputLevel() int {
+	return btp.params.ResidualParameters.MaxLevel()
+}
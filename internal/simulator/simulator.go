@@ -0,0 +1,441 @@
+// Package simulator implements an evaluator that runs a solution on the
+// cleartext slots of its ciphertexts while keeping track of their level,
+// scale and degree as the homomorphic evaluator would. It costs no
+// homomorphic operation and reports the depth consumed by the solution.
+package simulator
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+	"sync"
+
+	"github.com/tuneinsight/lattigo/v5/core/rlwe"
+	"github.com/tuneinsight/lattigo/v5/he/hefloat"
+	"github.com/tuneinsight/lattigo/v5/ring"
+	"github.com/tuneinsight/lattigo/v5/utils/bignum"
+
+	"app/internal/cleartext"
+	"app/utils"
+)
+
+// Operation is the record of a simulated operation.
+type Operation struct {
+	Name     string
+	Level    int     // Level of the result.
+	LogScale float64 // Log2 of the scale of the result.
+	Degree   int     // Degree of the result.
+}
+
+// Evaluator is a utils.Evaluator operating on the cleartext slots of the ciphertexts.
+// The ciphertexts it returns carry their metadata and, instead of their
+// polynomials, their slots: the real parts in the first row of Value[0] and
+// the imaginary parts in the first row of Value[1], so that they survive
+// rlwe.Ciphertext.CopyNew. A ciphertext allocated with hefloat.NewCiphertext
+// is thus an encryption of zero, and an actual encryption is meaningless.
+type Evaluator struct {
+	params hefloat.Parameters
+	ecd    *hefloat.Encoder
+
+	mu sync.Mutex
+
+	// Trace is the list of the operations evaluated so far.
+	Trace []Operation
+}
+
+var _ utils.Evaluator = (*Evaluator)(nil)
+var _ utils.ShallowCopier = (*Evaluator)(nil)
+var _ utils.Sequential = (*Evaluator)(nil)
+
+// NewEvaluator returns a new simulated Evaluator.
+func NewEvaluator(params hefloat.Parameters) *Evaluator {
+	return &Evaluator{
+		params: params,
+		ecd:    hefloat.NewEncoder(params),
+	}
+}
+
+// ShallowCopy returns the Evaluator itself, which can be used concurrently:
+// its ciphertexts hold their own slots, and the trace is shared. The trace
+// then lists the operations in the order of the scheduling, which is why the
+// Evaluator is Sequential.
+func (eval *Evaluator) ShallowCopy() (utils.Evaluator, error) {
+	return eval, nil
+}
+
+// Sequential returns true: a utils.Pool evaluates its pipelines one after the
+// other with the Evaluator, so that the trace is deterministic.
+func (eval *Evaluator) Sequential() bool {
+	return true
+}
+
+// NewCiphertext returns a simulated ciphertext of degree one at the given
+// level and default scale, holding the given values.
+func (eval *Evaluator) NewCiphertext(values []complex128, level int) (ct *rlwe.Ciphertext) {
+	ct = &rlwe.Ciphertext{}
+	ct.MetaData = &rlwe.MetaData{}
+	ct.Scale = eval.params.DefaultScale()
+	ct.LogDimensions = eval.params.LogMaxDimensions()
+	ct.IsBatched = true
+	ct.IsNTT = eval.params.NTTFlag()
+	setShape(ct, 1, level)
+	eval.Track(ct, values)
+	return
+}
+
+// Track sets the slots of ct.
+func (eval *Evaluator) Track(ct *rlwe.Ciphertext, values []complex128) {
+	slots := ct.Slots()
+	re, im := make([]uint64, slots), make([]uint64, slots)
+	for i := 0; i < min(slots, len(values)); i++ {
+		re[i], im[i] = math.Float64bits(real(values[i])), math.Float64bits(imag(values[i]))
+	}
+	ct.Value[0].Coeffs[0], ct.Value[1].Coeffs[0] = re, im
+}
+
+// Values returns the slots of ct.
+func (eval *Evaluator) Values(ct *rlwe.Ciphertext) []complex128 {
+	return eval.operand(ct, ct.Slots())
+}
+
+// MinLevel returns the lowest level reached by an operation.
+func (eval *Evaluator) MinLevel() (level int) {
+	eval.mu.Lock()
+	defer eval.mu.Unlock()
+	level = eval.params.MaxLevel()
+	for _, op := range eval.Trace {
+		level = min(level, op.Level)
+	}
+	return
+}
+
+// Depth returns the number of levels consumed by the operations evaluated so far.
+func (eval *Evaluator) Depth() int {
+	return eval.params.MaxLevel() - eval.MinLevel()
+}
+
+// Count returns the number of operations whose name is among names.
+func (eval *Evaluator) Count(names ...string) (n int) {
+	eval.mu.Lock()
+	defer eval.mu.Unlock()
+	for _, op := range eval.Trace {
+		for _, name := range names {
+			if op.Name == name {
+				n++
+			}
+		}
+	}
+	return
+}
+
+func (eval *Evaluator) GetRLWEParameters() *rlwe.Parameters {
+	return eval.params.GetRLWEParameters()
+}
+
+func (eval *Evaluator) GetParameters() *hefloat.Parameters {
+	return &eval.params
+}
+
+// GetEvaluatorBuffer returns nil: the simulated Evaluator does not operate on polynomials.
+func (eval *Evaluator) GetEvaluatorBuffer() *rlwe.EvaluatorBuffers {
+	return nil
+}
+
+// setShape resizes ct to the given degree and level without allocating its coefficients.
+func setShape(ct *rlwe.Ciphertext, degree, level int) {
+	ct.Value = make([]ring.Poly, degree+1)
+	for i := range ct.Value {
+		ct.Value[i] = ring.Poly{Coeffs: make([][]uint64, level+1)}
+	}
+}
+
+// output returns opOut, or a new ciphertext if opOut is nil, with the given shape and metadata.
+func (eval *Evaluator) output(name string, opOut *rlwe.Ciphertext, md *rlwe.MetaData, degree, level int, values []complex128) *rlwe.Ciphertext {
+
+	if opOut == nil {
+		opOut = &rlwe.Ciphertext{}
+	}
+
+	opOut.MetaData = md.CopyNew()
+	setShape(opOut, degree, level)
+	eval.Track(opOut, values)
+
+	eval.mu.Lock()
+	eval.Trace = append(eval.Trace, Operation{Name: name, Level: level, LogScale: opOut.LogScale(), Degree: degree})
+	eval.mu.Unlock()
+
+	return opOut
+}
+
+// operand returns the slots of a ciphertext, plaintext, scalar or vector operand.
+func (eval *Evaluator) operand(op interface{}, slots int) (v []complex128) {
+	switch op := op.(type) {
+	case *rlwe.Ciphertext:
+		v = make([]complex128, slots)
+		re, im := op.Value[0].Coeffs[0], op.Value[1].Coeffs[0]
+		for i := 0; i < min(slots, len(re), len(im)); i++ {
+			v[i] = complex(math.Float64frombits(re[i]), math.Float64frombits(im[i]))
+		}
+		return
+	case *rlwe.Plaintext:
+		v = make([]complex128, op.Slots())
+		eval.mu.Lock()
+		err := eval.ecd.Decode(op, v)
+		eval.mu.Unlock()
+		if err != nil {
+			panic(err)
+		}
+		if len(v) < slots {
+			v = append(v, make([]complex128, slots-len(v))...)
+		}
+		return v[:slots]
+	}
+
+	if v, ok := cleartext.FromOperand(op, slots); ok {
+		return v
+	}
+
+	panic(fmt.Errorf("simulator: unsupported operand type %T", op))
+}
+
+// rescalingFactor returns the product of the moduli consumed by a rescaling at the given level.
+func (eval *Evaluator) rescalingFactor(level int) (scale rlwe.Scale) {
+	scale = rlwe.NewScale(1)
+	for i := 0; i < eval.params.LevelsConsumedPerRescaling() && level-i >= 0; i++ {
+		scale = scale.Mul(rlwe.NewScale(eval.params.Q()[level-i]))
+	}
+	return
+}
+
+// operandScale returns the scale by which op1 is multiplied when it is the
+// operand of a multiplication at the given level.
+func (eval *Evaluator) operandScale(op1 interface{}, level int) rlwe.Scale {
+	switch op1 := op1.(type) {
+	case *rlwe.Ciphertext:
+		return op1.Scale
+	case *rlwe.Plaintext:
+		return op1.Scale
+	case []complex128, []float64, []*big.Float, []*bignum.Complex:
+		return eval.rescalingFactor(level)
+	}
+
+	// Gaussian integers are not scaled
+	if v, ok := cleartext.FromOperand(op1, 1); ok {
+		if x := v[0]; real(x) == math.Trunc(real(x)) && imag(x) == math.Trunc(imag(x)) {
+			return rlwe.NewScale(1)
+		}
+	}
+
+	return eval.rescalingFactor(level)
+}
+
+// shape returns the level and degree of op1, or false if it is not a ciphertext or plaintext.
+func shape(op1 interface{}) (level, degree int, ok bool) {
+	switch op1 := op1.(type) {
+	case *rlwe.Ciphertext:
+		return op1.Level(), op1.Degree(), true
+	case *rlwe.Plaintext:
+		return op1.Level(), 0, true
+	}
+	return 0, 0, false
+}
+
+func (eval *Evaluator) add(name string, op0 *rlwe.Ciphertext, op1 rlwe.Operand, opOut *rlwe.Ciphertext, f func(a, b []complex128) []complex128) (*rlwe.Ciphertext, error) {
+
+	level, degree := op0.Level(), op0.Degree()
+	md := *op0.MetaData
+
+	if l1, d1, ok := shape(op1); ok {
+		level, degree = min(level, l1), max(degree, d1)
+		if ct, ok := op1.(*rlwe.Ciphertext); ok && ct.Scale.Cmp(md.Scale) == 1 {
+			md.Scale = ct.Scale
+		}
+	}
+
+	if opOut != nil {
+		level = min(level, opOut.Level())
+	}
+
+	slots := op0.Slots()
+	return eval.output(name, opOut, &md, degree, level, f(eval.operand(op0, slots), eval.operand(op1, slots))), nil
+}
+
+func (eval *Evaluator) mul(name string, op0 *rlwe.Ciphertext, op1 rlwe.Operand, opOut *rlwe.Ciphertext, relin bool) (*rlwe.Ciphertext, error) {
+
+	level, degree := op0.Level(), op0.Degree()
+	md := *op0.MetaData
+
+	if l1, d1, ok := shape(op1); ok {
+		if degree+d1 > 2 {
+			return nil, fmt.Errorf("cannot %s: the input degrees sum to %d > 2", name, degree+d1)
+		}
+		level, degree = min(level, l1), degree+d1
+	}
+
+	if opOut != nil {
+		level = min(level, opOut.Level())
+	}
+
+	if relin {
+		degree = 1
+	}
+
+	md.Scale = md.Scale.Mul(eval.operandScale(op1, level))
+
+	slots := op0.Slots()
+	return eval.output(name, opOut, &md, degree, level, cleartext.Mul(eval.operand(op0, slots), eval.operand(op1, slots))), nil
+}
+
+func (eval *Evaluator) mulThenAdd(name string, op0 *rlwe.Ciphertext, op1 rlwe.Operand, opOut *rlwe.Ciphertext, relin bool) (err error) {
+
+	if opOut == op0 || opOut == op1 {
+		return fmt.Errorf("cannot %s: opOut must be different from op0 and op1", name)
+	}
+
+	level, degree := min(op0.Level(), opOut.Level()), max(op0.Degree(), opOut.Degree())
+	md := *opOut.MetaData
+
+	if l1, d1, ok := shape(op1); ok {
+		level = min(level, l1)
+		if !relin {
+			degree = max(degree, op0.Degree()+d1)
+		}
+	} else if op0.Scale.Cmp(opOut.Scale) == 0 {
+		// opOut is scaled up so that op0 * op1 can be added to it
+		md.Scale = md.Scale.Mul(eval.operandScale(op1, level))
+	} else if op0.Scale.Cmp(opOut.Scale) == 1 {
+		return fmt.Errorf("cannot %s: op0.Scale > opOut.Scale is not supported", name)
+	}
+
+	slots := op0.Slots()
+	acc := eval.operand(opOut, slots)
+	eval.output(name, opOut, &md, degree, level, cleartext.Add(acc, cleartext.Mul(eval.operand(op0, slots), eval.operand(op1, slots))))
+	return
+}
+
+func (eval *Evaluator) unary(name string, op0, opOut *rlwe.Ciphertext, degree, level int, f func(a []complex128) []complex128) *rlwe.Ciphertext {
+	md := *op0.MetaData
+	return eval.output(name, opOut, &md, degree, level, f(eval.operand(op0, op0.Slots())))
+}
+
+func (eval *Evaluator) Add(op0 *rlwe.Ciphertext, op1 rlwe.Operand, opOut *rlwe.Ciphertext) (err error) {
+	_, err = eval.add("Add", op0, op1, opOut, cleartext.Add)
+	return
+}
+
+func (eval *Evaluator) AddNew(op0 *rlwe.Ciphertext, op1 rlwe.Operand) (opOut *rlwe.Ciphertext, err error) {
+	return eval.add("Add", op0, op1, nil, cleartext.Add)
+}
+
+func (eval *Evaluator) Sub(op0 *rlwe.Ciphertext, op1 rlwe.Operand, opOut *rlwe.Ciphertext) (err error) {
+	_, err = eval.add("Sub", op0, op1, opOut, cleartext.Sub)
+	return
+}
+
+func (eval *Evaluator) SubNew(op0 *rlwe.Ciphertext, op1 rlwe.Operand) (opOut *rlwe.Ciphertext, err error) {
+	return eval.add("Sub", op0, op1, nil, cleartext.Sub)
+}
+
+func (eval *Evaluator) Mul(op0 *rlwe.Ciphertext, op1 rlwe.Operand, opOut *rlwe.Ciphertext) (err error) {
+	_, err = eval.mul("Mul", op0, op1, opOut, false)
+	return
+}
+
+func (eval *Evaluator) MulNew(op0 *rlwe.Ciphertext, op1 rlwe.Operand) (opOut *rlwe.Ciphertext, err error) {
+	return eval.mul("Mul", op0, op1, nil, false)
+}
+
+func (eval *Evaluator) MulRelin(op0 *rlwe.Ciphertext, op1 rlwe.Operand, opOut *rlwe.Ciphertext) (err error) {
+	_, err = eval.mul("MulRelin", op0, op1, opOut, true)
+	return
+}
+
+func (eval *Evaluator) MulRelinNew(op0 *rlwe.Ciphertext, op1 rlwe.Operand) (opOut *rlwe.Ciphertext, err error) {
+	return eval.mul("MulRelin", op0, op1, nil, true)
+}
+
+func (eval *Evaluator) MulThenAdd(op0 *rlwe.Ciphertext, op1 rlwe.Operand, opOut *rlwe.Ciphertext) (err error) {
+	return eval.mulThenAdd("MulThenAdd", op0, op1, opOut, false)
+}
+
+func (eval *Evaluator) MulRelinThenAdd(op0 *rlwe.Ciphertext, op1 rlwe.Operand, opOut *rlwe.Ciphertext) (err error) {
+	return eval.mulThenAdd("MulRelinThenAdd", op0, op1, opOut, true)
+}
+
+func (eval *Evaluator) Relinearize(op0, opOut *rlwe.Ciphertext) (err error) {
+	if op0.Degree() != 2 {
+		return fmt.Errorf("cannot Relinearize: input degree should be 2 but is %d", op0.Degree())
+	}
+	eval.unary("Relinearize", op0, opOut, 1, min(op0.Level(), opOut.Level()), identity)
+	return
+}
+
+func (eval *Evaluator) RelinearizeNew(op0 *rlwe.Ciphertext) (opOut *rlwe.Ciphertext, err error) {
+	if op0.Degree() != 2 {
+		return nil, fmt.Errorf("cannot Relinearize: input degree should be 2 but is %d", op0.Degree())
+	}
+	return eval.unary("Relinearize", op0, nil, 1, op0.Level(), identity), nil
+}
+
+func (eval *Evaluator) Rescale(op0, opOut *rlwe.Ciphertext) (err error) {
+
+	nbRescales := eval.params.LevelsConsumedPerRescaling()
+
+	if op0.Level() <= nbRescales-1 {
+		return fmt.Errorf("cannot Rescale: input Ciphertext level is too low")
+	}
+
+	md := *op0.MetaData
+	md.Scale = md.Scale.Div(eval.rescalingFactor(op0.Level()))
+
+	eval.output("Rescale", opOut, &md, op0.Degree(), op0.Level()-nbRescales, eval.operand(op0, op0.Slots()))
+	return
+}
+
+func (eval *Evaluator) Rotate(op0 *rlwe.Ciphertext, k int, opOut *rlwe.Ciphertext) (err error) {
+	if op0.Degree() != 1 {
+		return fmt.Errorf("cannot Rotate: input degree should be 1 but is %d", op0.Degree())
+	}
+	eval.unary("Rotate", op0, opOut, 1, min(op0.Level(), opOut.Level()), func(a []complex128) []complex128 { return cleartext.Rotate(a, k) })
+	return
+}
+
+func (eval *Evaluator) RotateNew(op0 *rlwe.Ciphertext, k int) (opOut *rlwe.Ciphertext, err error) {
+	if op0.Degree() != 1 {
+		return nil, fmt.Errorf("cannot Rotate: input degree should be 1 but is %d", op0.Degree())
+	}
+	return eval.unary("Rotate", op0, nil, 1, op0.Level(), func(a []complex128) []complex128 { return cleartext.Rotate(a, k) }), nil
+}
+
+func (eval *Evaluator) Conjugate(op0, opOut *rlwe.Ciphertext) (err error) {
+	if eval.params.RingType() == ring.ConjugateInvariant {
+		return fmt.Errorf("cannot Conjugate: method is not supported when params.RingType() == ring.ConjugateInvariant")
+	}
+	if op0.Degree() != 1 {
+		return fmt.Errorf("cannot Conjugate: input degree should be 1 but is %d", op0.Degree())
+	}
+	eval.unary("Conjugate", op0, opOut, 1, min(op0.Level(), opOut.Level()), cleartext.Conjugate)
+	return
+}
+
+func (eval *Evaluator) ConjugateNew(op0 *rlwe.Ciphertext) (opOut *rlwe.Ciphertext, err error) {
+	if eval.params.RingType() == ring.ConjugateInvariant {
+		return nil, fmt.Errorf("cannot Conjugate: method is not supported when params.RingType() == ring.ConjugateInvariant")
+	}
+	if op0.Degree() != 1 {
+		return nil, fmt.Errorf("cannot Conjugate: input degree should be 1 but is %d", op0.Degree())
+	}
+	return eval.unary("Conjugate", op0, nil, 1, op0.Level(), cleartext.Conjugate), nil
+}
+
+func (eval *Evaluator) DropLevel(op0 *rlwe.Ciphertext, levels int) {
+	eval.unary("DropLevel", op0, op0, op0.Degree(), op0.Level()-levels, identity)
+}
+
+func (eval *Evaluator) DropLevelNew(op0 *rlwe.Ciphertext, levels int) (opOut *rlwe.Ciphertext) {
+	return eval.unary("DropLevel", op0, nil, op0.Degree(), op0.Level()-levels, identity)
+}
+
+func identity(a []complex128) []complex128 {
+	return a
+}
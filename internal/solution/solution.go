@@ -1,47 +1,69 @@
 package solution
 
 import (
+	"context"
+
 	"github.com/tuneinsight/lattigo/v5/core/rlwe"
-	"github.com/tuneinsight/lattigo/v5/he/hefloat"
-	"github.com/tuneinsight/lattigo/v5/he/hefloat/bootstrapping"
+	"github.com/tuneinsight/lattigo/v5/he"
 	"app/utils"
 )
 
+// SolveTestcase receives the inputs of the testcase by name and returns its
+// outputs by name. A vector longer than the slots of a ciphertext is split
+// across several ciphertexts (see utils.CiphertextVector). The challenges
+// with a single operand have the input "in" and the output "out", the
+// "compare" challenge the inputs "a" and "b" and the output "out".
+// ctx is cancelled when the time limit of main.go --timeout is reached, after
+// which the operations of eval return its error.
 func SolveTestcase(
+	ctx context.Context,
 	params utils.Parameters,
 	evk utils.EvaluationKeySet,
-	in *rlwe.Ciphertext,
-) (out *rlwe.Ciphertext, err error) {
-
-	paramsScheme := params.Scheme
-	paramsBootstrapping := params.Bootstrapping
+	eval utils.Evaluator,
+	in utils.Ciphertexts,
+) (out utils.Ciphertexts, err error) {
 
-	// Instantiate the evaluator with the evaluation keys
-	eval := hefloat.NewEvaluator(paramsScheme, evk.Scheme)
+	var x utils.CiphertextVector
+	if x, err = in.Get("in"); err != nil{
+		return
+	}
 
-	if err = eval.Conjugate(in, in); err != nil{
+	// Instantiate the bootstrapper (nil if the bootstrapping is disabled): a bootstrapping.Evaluator
+	// with the keys evk.Bootstrapping (read from disk with main.go --lazy-keys), or the simulated
+	// bootstrapper of simulate.go, which passes the values through and resets their level
+	var btp he.Bootstrapper[rlwe.Ciphertext]
+	if btp, err = utils.NewBootstrapper(params, evk, eval); err != nil{
 		return
 	}
 
-	// Instantiate the bootstrapping Evaluator (if enabled)
-	var btp *bootstrapping.Evaluator
-	if paramsBootstrapping != nil{
-		if btp, err = bootstrapping.NewEvaluator(*paramsBootstrapping, evk.Bootstrapping); err != nil{
+	// The ciphertexts are evaluated concurrently by the workers of the pool, each with its own
+	// shallow copy of eval and btp (runtime.GOMAXPROCS(0) workers at most with a parallelism of 0)
+	pool := utils.NewPool(eval, btp, 0)
+
+	// The pool stops handing out the ciphertexts once the time limit is reached
+	y, err := pool.MapVector(ctx, x, func(w utils.Worker, ct *rlwe.Ciphertext) (res *rlwe.Ciphertext, err error) {
+
+		// w.Evaluator is a copy of eval, a *hefloat.Evaluator instantiated with the evaluation keys evk.Scheme
+		// (wrapped by the debug evaluator with main.go --debug-sk and by the profiling
+		// evaluator with main.go --profile, replaced by the cleartext simulator with simulate.go)
+		if err = w.Evaluator.Conjugate(ct, ct); err != nil{
 			return
 		}
-	}
 
-	if btp != nil{
-		// bootstrapping.Evaluator is compliant to the interface he.Bootstrapper[rlwe.Ciphertext] (/he/bootstrapper.go)
-		// see /he/hefloat/bootstrapping/bootstrapping for individual methods of the bootstrapping evaluator
-		// see examples/single_party/applications/reals_bootstrapping for bootstrapping examples
-		if out, err = btp.Bootstrap(in); err != nil{
-			return
+		if w.Bootstrapper != nil{
+			// bootstrapping.Evaluator is compliant to the interface he.Bootstrapper[rlwe.Ciphertext] (/he/bootstrapper.go)
+			// see /he/hefloat/bootstrapping/bootstrapping for individual methods of the bootstrapping evaluator
+			// see examples/single_party/applications/reals_bootstrapping for bootstrapping examples
+			return w.Bootstrapper.Bootstrap(ct)
 		}
-	}else{
-		out = in
+
+		return ct, nil
+	})
+
+	if err != nil{
+		return
 	}
 
 	// Put your solution here
-	return out, nil
+	return utils.Ciphertexts{"out": y}, nil
 }
@@ -0,0 +1,141 @@
+package submission
+
+import (
+	"archive/zip"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Modified is the modification time of every file of the archive, the
+// earliest of the zip format, so that the archive only depends on the
+// contents of the files.
+var Modified = time.Date(1980, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// Exclusion is a file left out of the archive.
+type Exclusion struct {
+	Path   string
+	Reason string
+}
+
+// artifacts are the extensions of the files left out of the archive.
+var artifacts = map[string]string{
+	".bin":    "keys or ciphertexts",
+	".zip":    "archive",
+	".exe":    "build artifact",
+	".test":   "build artifact",
+	".out":    "build artifact",
+	".o":      "build artifact",
+	".a":      "build artifact",
+	".so":     "build artifact",
+	".prof":   "profile",
+	".folded": "profile",
+}
+
+// Exclude returns why the entry at path, relative to the root of the template
+// and with forward slashes, is left out of the archive, or "" if it is
+// included. It leaves out the hidden files and folders, the contents of temps/
+// but temps/donotremove.txt, the keys and ciphertexts, the archives, the
+// executables and the other build artifacts.
+func Exclude(path string, d fs.DirEntry) string {
+
+	name := d.Name()
+
+	switch {
+	case strings.HasPrefix(name, ".") && path != ".":
+		return "hidden"
+	case strings.HasPrefix(path, "temps/") && path != "temps/donotremove.txt":
+		return "temporary file"
+	case d.Type()&fs.ModeSymlink != 0:
+		return "symbolic link"
+	case d.IsDir():
+		return ""
+	case artifacts[filepath.Ext(name)] != "":
+		return artifacts[filepath.Ext(name)]
+	case !d.Type().IsRegular():
+		return "not a regular file"
+	}
+
+	if info, err := d.Info(); err == nil && info.Mode()&0111 != 0 {
+		return "executable"
+	}
+
+	return ""
+}
+
+// Archive writes to w the zip of the files of the folder root that Exclude
+// keeps, in the folder prefix of the archive, and returns the paths of the
+// files written and the files left out. The archive is deterministic: the
+// files are sorted, and their times and permissions are fixed.
+func Archive(w io.Writer, root, prefix string) (files []string, excluded []Exclusion, err error) {
+
+	zw := zip.NewWriter(w)
+
+	err = filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(root, p)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+
+		if reason := Exclude(rel, d); reason != "" {
+
+			excluded = append(excluded, Exclusion{Path: rel, Reason: reason})
+
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		if d.IsDir() {
+			return nil
+		}
+
+		header := &zip.FileHeader{
+			Name:     path.Join(prefix, rel),
+			Method:   zip.Deflate,
+			Modified: Modified,
+		}
+		header.SetMode(0644)
+
+		fw, err := zw.CreateHeader(header)
+		if err != nil {
+			return fmt.Errorf("zip.Writer.CreateHeader: %w", err)
+		}
+
+		f, err := os.Open(p)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+
+		if _, err = io.Copy(fw, f); err != nil {
+			return fmt.Errorf("io.Copy(%s): %w", p, err)
+		}
+
+		files = append(files, rel)
+
+		return nil
+	})
+
+	if err != nil {
+		return nil, nil, fmt.Errorf("filepath.WalkDir(%s): %w", root, err)
+	}
+
+	if err = zw.Close(); err != nil {
+		return nil, nil, fmt.Errorf("zip.Writer.Close: %w", err)
+	}
+
+	return
+}
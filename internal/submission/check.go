@@ -0,0 +1,214 @@
+// Package submission checks the solution and builds the archive submitted
+// to the platform, see package.go.
+package submission
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"io/fs"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Params and Results are the types of the parameters and of the results of
+// SolveTestcase, with the package app/utils imported as utils, as called by main.go.
+var (
+	Params  = []string{"context.Context", "utils.Parameters", "utils.EvaluationKeySet", "utils.Evaluator", "utils.Ciphertexts"}
+	Results = []string{"utils.Ciphertexts", "error"}
+)
+
+// Module is the path of the module of the template.
+const Module = "app"
+
+// Lattigo is the only module the solution can import besides the standard library and the template.
+const Lattigo = "github.com/tuneinsight/lattigo/v5"
+
+// denied lists the packages of the standard library, and their sub-packages,
+// that the solution cannot import: cgo, unsafe memory accesses, processes and network.
+var denied = []string{"C", "unsafe", "syscall", "os/exec", "os/signal", "plugin", "net", "runtime/cgo"}
+
+// CheckImport returns an error if the solution cannot import the package path.
+func CheckImport(path string) error {
+
+	for _, d := range denied {
+		if path == d || strings.HasPrefix(path, d+"/") {
+			return fmt.Errorf("package %q is not allowed", path)
+		}
+	}
+
+	switch first := strings.Split(path, "/")[0]; {
+	case first == Module, path == Lattigo, strings.HasPrefix(path, Lattigo+"/"):
+		return nil
+	case !strings.Contains(first, "."):
+		return nil // Standard library
+	default:
+		return fmt.Errorf("package %q is outside of the standard library, %s and the template", path, Lattigo)
+	}
+}
+
+// CheckImports returns an error listing the imports that the solution cannot
+// use in the package in dir and in every package of the template it depends
+// on, directly or not, tests excluded. The packages of the template are thus
+// only allowed if they do not import denied packages themselves, and a package
+// added next to the solution cannot be used to reach a denied package.
+func CheckImports(dir string) error {
+
+	pkgs, err := deps(dir)
+	if err != nil {
+		return err
+	}
+
+	var errs []string
+
+	for _, pkg := range pkgs {
+
+		if pkg.ImportPath != Module && !strings.HasPrefix(pkg.ImportPath, Module+"/") {
+			continue
+		}
+
+		for _, name := range append(pkg.GoFiles, pkg.CgoFiles...) {
+
+			path := filepath.Join(pkg.Dir, name)
+
+			fset := token.NewFileSet()
+			f, err := parser.ParseFile(fset, path, nil, parser.ImportsOnly)
+			if err != nil {
+				return fmt.Errorf("parser.ParseFile: %w", err)
+			}
+
+			for _, spec := range f.Imports {
+				imp, _ := strconv.Unquote(spec.Path.Value)
+				if err := CheckImport(imp); err != nil {
+					errs = append(errs, fmt.Sprintf("%s (%s): %s", fset.Position(spec.Pos()), pkg.ImportPath, err))
+				}
+			}
+		}
+	}
+
+	if len(errs) > 0 {
+		sort.Strings(errs)
+		return fmt.Errorf("forbidden imports:\n%s", strings.Join(errs, "\n"))
+	}
+
+	return nil
+}
+
+// pkg is the subset of the output of go list used by CheckImports.
+type pkg struct {
+	ImportPath string
+	Dir        string
+	GoFiles    []string
+	CgoFiles   []string
+}
+
+// deps returns the package in dir and all its dependencies, with go list -deps.
+func deps(dir string) (pkgs []pkg, err error) {
+
+	var stdout, stderr bytes.Buffer
+
+	/* #nosec G204 */
+	cmd := exec.Command("go", "list", "-deps", "-json=ImportPath,Dir,GoFiles,CgoFiles", ".")
+	cmd.Dir, cmd.Stdout, cmd.Stderr = dir, &stdout, &stderr
+
+	if err = cmd.Run(); err != nil {
+		return nil, fmt.Errorf("go list -deps %s: %w: %s", dir, err, strings.TrimSpace(stderr.String()))
+	}
+
+	for dec := json.NewDecoder(&stdout); dec.More(); {
+		var p pkg
+		if err = dec.Decode(&p); err != nil {
+			return nil, fmt.Errorf("go list -deps %s: json.Decoder.Decode: %w", dir, err)
+		}
+		pkgs = append(pkgs, p)
+	}
+
+	return
+}
+
+// CheckSignature returns an error if the package in dir does not declare the
+// function SolveTestcase with the parameters Params and the results Results.
+func CheckSignature(dir string) error {
+
+	fset := token.NewFileSet()
+	pkgs, err := parser.ParseDir(fset, dir, func(fi fs.FileInfo) bool { return !strings.HasSuffix(fi.Name(), "_test.go") }, 0)
+	if err != nil {
+		return fmt.Errorf("parser.ParseDir(%s): %w", dir, err)
+	}
+
+	for _, pkg := range pkgs {
+		for _, f := range pkg.Files {
+
+			// The local name of app/utils, if renamed
+			utils := "utils"
+			for _, spec := range f.Imports {
+				if imp, _ := strconv.Unquote(spec.Path.Value); imp == Module+"/utils" && spec.Name != nil {
+					utils = spec.Name.Name
+				}
+			}
+
+			for _, decl := range f.Decls {
+
+				fn, ok := decl.(*ast.FuncDecl)
+				if !ok || fn.Recv != nil || fn.Name.Name != "SolveTestcase" {
+					continue
+				}
+
+				params, results := fieldTypes(fn.Type.Params, utils), fieldTypes(fn.Type.Results, utils)
+
+				if !equal(params, Params) || !equal(results, Results) {
+					return fmt.Errorf("%s: SolveTestcase is func(%s) (%s) instead of func(%s) (%s)",
+						fset.Position(fn.Pos()),
+						strings.Join(params, ", "), strings.Join(results, ", "),
+						strings.Join(Params, ", "), strings.Join(Results, ", "))
+				}
+
+				return nil
+			}
+		}
+	}
+
+	return fmt.Errorf("%s: SolveTestcase is not declared", dir)
+}
+
+// fieldTypes returns the types of the fields, one per name, with the package
+// imported as utils renamed utils.
+func fieldTypes(fields *ast.FieldList, utils string) (t []string) {
+
+	if fields == nil {
+		return
+	}
+
+	for _, field := range fields.List {
+
+		s := types.ExprString(field.Type)
+		if utils != "utils" && strings.HasPrefix(s, utils+".") {
+			s = "utils." + strings.TrimPrefix(s, utils+".")
+		}
+
+		for i := 0; i < max(len(field.Names), 1); i++ {
+			t = append(t, s)
+		}
+	}
+
+	return
+}
+
+func equal(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,93 @@
+package submission
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// module writes the files, by path relative to the root, of a module named app
+// in a temporary folder, and returns the folder of its solution.
+func module(t *testing.T, files map[string]string) string {
+
+	root := t.TempDir()
+
+	files["go.mod"] = "module app\n\ngo 1.21\n"
+
+	for name, src := range files {
+		path := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(src), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return filepath.Join(root, "internal", "solution")
+}
+
+func TestCheckImports(t *testing.T) {
+
+	for _, tc := range []struct {
+		name  string
+		files map[string]string
+		err   string // Expected error, empty if allowed.
+	}{
+		{
+			name: "Allowed",
+			files: map[string]string{
+				"internal/solution/solution.go": "package solution\n\nimport _ \"app/helper\"\n",
+				"helper/helper.go":              "package helper\n\nimport _ \"math\"\n",
+			},
+		},
+		{
+			name: "Direct",
+			files: map[string]string{
+				"internal/solution/solution.go": "package solution\n\nimport _ \"os/exec\"\n",
+			},
+			err: `(app/internal/solution): package "os/exec" is not allowed`,
+		},
+		{
+			name: "ThroughPackage",
+			files: map[string]string{
+				"internal/solution/solution.go": "package solution\n\nimport _ \"app/helper\"\n",
+				"helper/helper.go":              "package helper\n\nimport _ \"app/helper/net\"\n",
+				"helper/net/net.go":             "package net\n\nimport _ \"net\"\n",
+			},
+			err: `(app/helper/net): package "net" is not allowed`,
+		},
+		{
+			name: "ThroughTemplate",
+			files: map[string]string{
+				"internal/solution/solution.go": "package solution\n\nimport _ \"app/internal/grader\"\n",
+				"internal/grader/grader.go":     "package grader\n\nimport _ \"unsafe\"\n",
+			},
+			err: `(app/internal/grader): package "unsafe" is not allowed`,
+		},
+		{
+			name: "Test",
+			files: map[string]string{
+				"internal/solution/solution.go":      "package solution\n",
+				"internal/solution/solution_test.go": "package solution\n\nimport _ \"net\"\n",
+			},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+
+			err := CheckImports(module(t, tc.files))
+
+			if tc.err == "" {
+				if err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+
+			if err == nil || !strings.Contains(err.Error(), tc.err) {
+				t.Fatalf("err=%v, want an error containing %q", err, tc.err)
+			}
+		})
+	}
+}
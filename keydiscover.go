@@ -0,0 +1,120 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/tuneinsight/lattigo/v5/core/rlwe"
+	"github.com/tuneinsight/lattigo/v5/he/hefloat"
+
+	"app/internal/challenge"
+	"app/internal/solution"
+	"app/utils"
+)
+
+// Dry-runs the solution with keys generated on demand and writes
+// the minimal "EvaluationKeys" block back into the configuration file.
+func main() {
+	configFile := flag.String("config", "config.json", "")
+	dryRun := flag.Bool("dry-run", false, "print the discovered keys without updating the configuration file")
+
+	flag.Parse()
+
+	dataJSON, err := os.ReadFile(*configFile)
+	if err != nil {
+		log.Fatalf("os.ReadFile(%s): %s", *configFile, err.Error())
+	}
+
+	params := utils.Parameters{}
+	if err := params.UnmarshalJSON(dataJSON); err != nil {
+		log.Fatalf("utils.Parameters.UnmarshalJSON: %s", err.Error())
+	}
+
+	sk := rlwe.NewKeyGenerator(params.Scheme).GenSecretKeyNew()
+
+	ecd := hefloat.NewEncoder(params.Scheme)
+
+	enc := rlwe.NewEncryptor(params.Scheme, sk)
+
+	chLit, err := challenge.LiteralFromJSON(dataJSON)
+	if err != nil {
+		log.Fatalf("challenge.LiteralFromJSON: %s", err.Error())
+	}
+
+	ch, err := chLit.Challenge()
+	if err != nil {
+		log.Fatalf("challenge.Literal.Challenge: %s", err.Error())
+	}
+
+	if err := challenge.CheckParameters(ch, params.Scheme); err != nil {
+		log.Fatalf("challenge.CheckParameters: %s", err.Error())
+	}
+
+	values, err := ch.GenInput(params.Scheme, chLit.Rand())
+	if err != nil {
+		log.Fatalf("challenge.Challenge.GenInput: %s", err.Error())
+	}
+
+	slots := ch.Layout(params.Scheme).Slots
+
+	in := utils.Ciphertexts{}
+	for _, name := range values.Names() {
+		if in[name], err = utils.EncryptVector(params.Scheme, ecd, enc, values[name], slots, params.Scheme.MaxLevel()); err != nil {
+			log.Fatalf("%s: utils.EncryptVector: %s", name, err.Error())
+		}
+	}
+
+	recorder := utils.NewKeyRecorder(params.Scheme, sk)
+	recorder.Logger = log.New(os.Stdout, "keydiscover: ", 0)
+
+	evk := utils.EvaluationKeySet{Scheme: recorder}
+
+	if params.Bootstrapping != nil {
+		if evk.Bootstrapping, _, err = params.Bootstrapping.GenEvaluationKeys(sk); err != nil {
+			log.Fatalf("bootstrapping.Parameters.GenEvaluationKeys: %s", err.Error())
+		}
+	}
+
+	if _, err = solution.SolveTestcase(context.Background(), params, evk, hefloat.NewEvaluator(params.Scheme, recorder), in); err != nil {
+		log.Fatalf("solution.SolveTestcase: %s", err.Error())
+	}
+
+	for _, galEl := range recorder.GaloisElements() {
+		fmt.Printf("GaloisKey[%d]: %d request(s)\n", galEl, recorder.GaloisRequests[galEl])
+	}
+	fmt.Printf("RelinearizationKey: %d request(s)\n", recorder.RelinearizationRequests)
+
+	lit := recorder.Literal()
+
+	// Keeps the key format of the configuration
+	aux := struct {
+		EvaluationKeys utils.EvaluationKeysLiteral
+	}{}
+
+	if err = json.Unmarshal(dataJSON, &aux); err != nil {
+		log.Fatalf("json.Unmarshal: %s", err.Error())
+	}
+
+	lit.Seeded = aux.EvaluationKeys.Seeded
+
+	if *dryRun {
+		data, err := json.MarshalIndent(lit, "", "    ")
+		if err != nil {
+			log.Fatalf("json.MarshalIndent: %s", err.Error())
+		}
+		fmt.Printf("%s\n", data)
+		return
+	}
+
+	if dataJSON, err = utils.ReplaceConfigField(dataJSON, "EvaluationKeys", lit); err != nil {
+		log.Fatalf("utils.ReplaceConfigField: %s", err.Error())
+	}
+
+	if err = os.WriteFile(*configFile, dataJSON, 0600); err != nil {
+		log.Fatalf("os.WriteFile(%s): %s", *configFile, err.Error())
+	}
+}
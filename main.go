@@ -1,47 +1,200 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"fmt"
 	"log"
+	"os"
 	"time"
 
 	"github.com/tuneinsight/lattigo/v5/core/rlwe"
+	"github.com/tuneinsight/lattigo/v5/he/hefloat"
 
+	"app/internal/debug"
+	"app/internal/profile"
 	"app/internal/solution"
 	"app/utils"
 )
 
+// exitTimeout is the exit status when the time limit is reached, as timeout(1).
+const exitTimeout = 124
+
 func main() {
 	now := time.Now()
 	cc := flag.String("cc", "", "")
 	evkFile := flag.String("key_eval", "", "")
 	inputFile := flag.String("input", "", "")
 	outputFile := flag.String("output", "", "")
+	runtimeFile := flag.String("runtime", "", "file to write the runtime to, read by verify.go")
+	debugSk := flag.String("debug-sk", "", "secret key file, decrypts and logs the result of every operation")
+	lazyKeys := flag.Bool("lazy-keys", false, "read the evaluation keys from disk on first use instead of at start")
+	keyBudget := flag.Int64("key-budget", 0, "with --lazy-keys, maximum size in MiB of the scheme keys kept in memory (0 for no limit)")
+	profileFile := flag.String("profile", "", "file to write the time spent in each phase and operation to, in the folded format of the flame graphs, also printed as a table")
+	timeout := flag.Duration("timeout", 0, "time limit of the run, after which the solution is cancelled and main.go exits with status 124 (0 for no limit)")
 
 	flag.Parse()
 
+	ctx := context.Background()
+	if *timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithDeadline(ctx, now.Add(*timeout))
+		defer cancel()
+	}
+
+	// A nil Profile records nothing
+	var prof *profile.Profile
+	if *profileFile != "" {
+		prof = profile.New()
+	}
+
+	end := prof.Begin("Deserialize")
+
 	params := utils.Parameters{}
 	evk := utils.EvaluationKeySet{}
-	in := rlwe.Ciphertext{}
+	in := utils.Ciphertexts{}
 
-	if err := utils.Deserialize(&params, *cc); err != nil {
+	if err := utils.Deserialize(&params, *cc, nil); err != nil {
 		log.Fatalf(err.Error())
 	}
 
-	if err := utils.Deserialize(&evk, *evkFile); err != nil {
+	if *lazyKeys {
+		kf, err := utils.OpenEvaluationKeySet(*evkFile, &params, *keyBudget<<20)
+		if err != nil {
+			log.Fatalf(err.Error())
+		}
+		defer kf.Close()
+
+		evk = kf.EvaluationKeySet()
+	} else if err := utils.Deserialize(&evk, *evkFile, &params); err != nil {
 		log.Fatalf(err.Error())
 	}
 
-	if err := utils.Deserialize(&in, *inputFile); err != nil {
+	if err := utils.Deserialize(&in, *inputFile, &params); err != nil {
 		log.Fatalf(err.Error())
 	}
 
-	out, err := solution.SolveTestcase(params, evk, &in)
+	end()
+
+	end = prof.Begin("Check")
+
+	if err := utils.CheckCompatibility(params, evk); err != nil {
+		log.Fatalf("incompatible inputs: %s", err.Error())
+	}
+
+	if err := utils.CheckCiphertexts(params.Scheme, in); err != nil {
+		log.Fatalf("incompatible inputs: %s", err.Error())
+	}
+
+	end()
+
+	end = prof.Begin("NewEvaluator")
+
+	var eval utils.Evaluator = hefloat.NewEvaluator(params.Scheme, evk.Scheme)
+
+	if *debugSk != "" {
+		sk := rlwe.SecretKey{}
+		if err := utils.Deserialize(&sk, *debugSk, &params); err != nil {
+			log.Fatalf("utils.Deserialize: %s", err.Error())
+		}
+
+		d := debug.NewEvaluator(params.Scheme, hefloat.NewEvaluator(params.Scheme, evk.Scheme), &sk, log.New(os.Stderr, "debug: ", 0))
+
+		// The shadows of the inputs are their decryption
+		for _, v := range in {
+			for _, ct := range v {
+				d.Track(ct, d.Decrypt(ct))
+			}
+		}
+
+		eval = d
+	}
+
+	// The operations of the evaluator check the time limit
+	if prof != nil || *timeout > 0 {
+		eval = profile.NewEvaluator(ctx, eval, prof)
+	}
+
+	end()
+
+	end = prof.Begin("SolveTestcase")
+
+	type result struct {
+		out utils.Ciphertexts
+		err error
+	}
+
+	// The solution runs apart so that a time limit reached in the middle of an
+	// operation that does not check it, such as a bootstrapping, stops main.go
+	done := make(chan result, 1)
+	go func() {
+		out, err := solution.SolveTestcase(ctx, params, evk, eval, in)
+		done <- result{out, err}
+	}()
+
+	var out utils.Ciphertexts
+	select {
+	case res := <-done:
+		if res.err != nil {
+			if ctx.Err() != nil {
+				timedOut(*timeout, prof, *profileFile)
+			}
+			log.Fatalf("solution.SolveTestcase: %s", res.err.Error())
+		}
+		out = res.out
+	case <-ctx.Done():
+		timedOut(*timeout, prof, *profileFile)
+	}
+
+	end()
+
+	end = prof.Begin("Serialize")
+
+	if err := utils.Serialize(out, *outputFile, &params); err != nil {
+		log.Fatalf("utils.Serialize: %s", err.Error())
+	}
+
+	end()
+
+	runtime := time.Since(now)
+
+	if *runtimeFile != "" {
+		if err := os.WriteFile(*runtimeFile, []byte(runtime.String()), 0600); err != nil {
+			log.Fatalf("os.WriteFile(%s): %s", *runtimeFile, err.Error())
+		}
+	}
+
+	writeProfile(prof, *profileFile)
+
+	fmt.Printf("Done: %s\n", runtime)
+}
+
+// timedOut writes the partial profile, up to the time limit, and exits with exitTimeout.
+func timedOut(timeout time.Duration, prof *profile.Profile, profileFile string) {
+	log.Printf("time limit of %s reached: the solution is cancelled", timeout)
+	writeProfile(prof, profileFile)
+	os.Exit(exitTimeout)
+}
+
+// writeProfile writes the profile to profileFile in the folded format and prints its summary, if prof is not nil.
+func writeProfile(prof *profile.Profile, profileFile string) {
+
+	if prof == nil {
+		return
+	}
+
+	f, err := os.Create(profileFile)
 	if err != nil {
-		log.Fatalf("solution.SolveTestcase: %s", err.Error())
+		log.Fatalf("os.Create(%s): %s", profileFile, err.Error())
+	}
+
+	if err := prof.WriteFolded(f); err != nil {
+		log.Fatalf("profile.WriteFolded(%s): %s", profileFile, err.Error())
+	}
+
+	if err := f.Close(); err != nil {
+		log.Fatalf("os.File.Close(%s): %s", profileFile, err.Error())
 	}
 
-	utils.Serialize(out, *outputFile)
-	fmt.Printf("Done: %s\n", time.Since(now))
+	fmt.Println(prof.Summary())
 }
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+
+	"app/internal/submission"
+)
+
+// Checks that the solution compiles with the signature of SolveTestcase
+// expected by main.go and only uses the allowed imports, and writes the
+// deterministic archive of the template to submit, without the temporary
+// files, the keys and the build artifacts.
+func main() {
+	output := flag.String("output", "app.zip", "file to write the archive to")
+	maxSize := flag.Float64("max-size", 10, "maximum size of the archive in MiB (a local default, not a limit documented by the platform)")
+	verbose := flag.Bool("v", false, "list the files written and left out")
+
+	flag.Parse()
+
+	if err := submission.CheckSignature("internal/solution"); err != nil {
+		log.Fatalf("submission.CheckSignature: %s", err.Error())
+	}
+
+	if err := submission.CheckImports("internal/solution"); err != nil {
+		log.Fatalf("submission.CheckImports: %s", err.Error())
+	}
+
+	// main.go calls SolveTestcase, so it only compiles if the solution does
+	build := exec.Command("go", "build", "-o", os.DevNull, "main.go")
+	build.Stdout, build.Stderr = os.Stdout, os.Stderr
+	if err := build.Run(); err != nil {
+		log.Fatalf("go build main.go: %s", err.Error())
+	}
+
+	var buf bytes.Buffer
+	files, excluded, err := submission.Archive(&buf, ".", submission.Module)
+	if err != nil {
+		log.Fatalf("submission.Archive: %s", err.Error())
+	}
+
+	if *verbose {
+		for _, f := range files {
+			fmt.Printf("+ %s\n", f)
+		}
+		for _, e := range excluded {
+			fmt.Printf("- %s (%s)\n", e.Path, e.Reason)
+		}
+	}
+
+	size := float64(buf.Len()) / (1 << 20)
+	if size > *maxSize {
+		log.Fatalf("the archive is %.2f MiB, more than %.2f MiB: remove the large files (see -v)", size, *maxSize)
+	}
+
+	if err := os.WriteFile(*output, buf.Bytes(), 0644); err != nil {
+		log.Fatalf("os.WriteFile(%s): %s", *output, err.Error())
+	}
+
+	fmt.Printf("%s: %d files, %.2f MiB, %d left out\n", *output, len(files), size, len(excluded))
+}
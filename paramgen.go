@@ -0,0 +1,95 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/tuneinsight/lattigo/v5/ring"
+
+	"app/utils"
+)
+
+// Generates the "Scheme" and "Bootstrapping" blocks meeting a target depth,
+// precision and security and writes them into the configuration file.
+func main() {
+	configFile := flag.String("config", "config.json", "")
+	depth := flag.Int("depth", 0, "number of rescalings of the circuit (between two bootstrappings with --btp)")
+	precision := flag.Float64("prec", 20, "target precision in bits")
+	btp := flag.Bool("btp", false, "enable bootstrapping")
+	security := flag.Float64("security", 0, "minimum bit security, defaults to Security.MinBits of the configuration file")
+	logSlots := flag.Int("logslots", 0, "minimum log2 number of slots")
+	ringType := flag.String("ring", "Standard", "ring type: Standard (complex slots) or ConjugateInvariant (real slots)")
+	dryRun := flag.Bool("dry-run", false, "print the generated parameters without updating the configuration file")
+
+	flag.Parse()
+
+	dataJSON, err := os.ReadFile(*configFile)
+	if err != nil {
+		log.Fatalf("os.ReadFile(%s): %s", *configFile, err.Error())
+	}
+
+	if *security == 0 {
+		if *security, err = utils.MinSecurityFromJSON(dataJSON); err != nil {
+			log.Fatalf("utils.MinSecurityFromJSON: %s", err.Error())
+		}
+	}
+
+	var rt ring.Type
+	if err = rt.UnmarshalJSON([]byte(fmt.Sprintf("%q", *ringType))); err != nil {
+		log.Fatalf("invalid --ring: %s", err.Error())
+	}
+
+	target := utils.ParametersTarget{
+		Depth:         *depth,
+		Precision:     *precision,
+		Bootstrapping: *btp,
+		Security:      *security,
+		LogSlots:      *logSlots,
+		RingType:      rt,
+	}
+
+	cfg, params, err := utils.GenParameters(target)
+	if err != nil {
+		log.Fatalf("utils.GenParameters: %s", err.Error())
+	}
+
+	estimates, err := params.Security()
+	if err != nil {
+		log.Fatalf("utils.Parameters.Security: %s", err.Error())
+	}
+
+	for _, e := range estimates {
+		fmt.Printf("security: %s\n", e)
+	}
+
+	if *dryRun {
+		data, err := json.MarshalIndent(cfg, "", "    ")
+		if err != nil {
+			log.Fatalf("json.MarshalIndent: %s", err.Error())
+		}
+		fmt.Printf("%s\n", data)
+		return
+	}
+
+	fields := []struct {
+		key   string
+		value interface{}
+	}{
+		{"Security", map[string]float64{"MinBits": *security}},
+		{"Scheme", cfg.Scheme},
+		{"Bootstrapping", cfg.Bootstrapping},
+	}
+
+	for _, f := range fields {
+		if dataJSON, err = utils.ReplaceConfigField(dataJSON, f.key, f.value); err != nil {
+			log.Fatalf("utils.ReplaceConfigField: %s", err.Error())
+		}
+	}
+
+	if err = os.WriteFile(*configFile, dataJSON, 0600); err != nil {
+		log.Fatalf("os.WriteFile(%s): %s", *configFile, err.Error())
+	}
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"app/utils"
+	"app/utils/planner"
+)
+
+// Plans the levels of the circuit declared in a JSON file with the parameters
+// of the configuration file, and prints the timeline of the levels.
+// Exits with status 1 if the circuit does not fit in the parameters.
+func main() {
+	configFile := flag.String("config", "config.json", "")
+	circuitFile := flag.String("circuit", "circuit.json", "JSON array of the stages of the circuit")
+
+	flag.Parse()
+
+	dataJSON, err := os.ReadFile(*configFile)
+	if err != nil {
+		log.Fatalf("os.ReadFile(%s): %s", *configFile, err.Error())
+	}
+
+	params := utils.Parameters{}
+	if err := params.UnmarshalJSON(dataJSON); err != nil {
+		log.Fatalf("utils.Parameters.UnmarshalJSON: %s", err.Error())
+	}
+
+	circuitJSON, err := os.ReadFile(*circuitFile)
+	if err != nil {
+		log.Fatalf("os.ReadFile(%s): %s", *circuitFile, err.Error())
+	}
+
+	circuit, err := planner.CircuitFromJSON(circuitJSON)
+	if err != nil {
+		log.Fatalf("planner.CircuitFromJSON(%s): %s", *circuitFile, err.Error())
+	}
+
+	plan, err := planner.New(params, circuit)
+	if err != nil {
+		log.Fatalf("planner.New: %s", err.Error())
+	}
+
+	fmt.Println(plan)
+
+	if plan.Err() != nil {
+		os.Exit(1)
+	}
+}
@@ -1,14 +1,15 @@
 package main
 
 import (
+	"app/internal/challenge"
 	"app/utils"
 	"flag"
 	"log"
-	"math/rand"
 	"os"
 
 	"github.com/tuneinsight/lattigo/v5/core/rlwe"
 	"github.com/tuneinsight/lattigo/v5/he/hefloat"
+	"github.com/tuneinsight/lattigo/v5/ring"
 )
 
 func main() {
@@ -16,6 +17,7 @@ func main() {
 	skFile := flag.String("sk", "", "")
 	evkFile := flag.String("key_eval", "", "")
 	inputFile := flag.String("input", "", "")
+	testcase := flag.Int("case", -1, "testcase of the \"Suite\" block of config.json, the \"Challenge\" block as is if negative")
 
 	flag.Parse()
 
@@ -29,41 +31,67 @@ func main() {
 		log.Fatalf(err.Error())
 	}
 
+	minSecurity, err := utils.MinSecurityFromJSON(dataJSON)
+	if err != nil {
+		log.Fatalf("utils.MinSecurityFromJSON: %s", err.Error())
+	}
+
+	estimates, err := params.CheckSecurity(minSecurity)
+	for _, e := range estimates {
+		log.Printf("security: %s", e)
+	}
+	if err != nil {
+		log.Fatalf("utils.Parameters.CheckSecurity: %s", err.Error())
+	}
+
 	sk := rlwe.NewKeyGenerator(params.Scheme).GenSecretKeyNew()
 
 	ecd := hefloat.NewEncoder(params.Scheme)
 
 	enc := rlwe.NewEncryptor(params.Scheme, sk)
 
-	/* #nosec G404 */
-	r := rand.New(rand.NewSource(0))
-	values := make([]complex128, params.Scheme.MaxSlots())
-	for i := range values {
-		values[i] = complex(2*r.Float64()-1, 2*r.Float64()-1)
+	lit, err := challenge.TestcaseFromJSON(dataJSON, *testcase)
+	if err != nil {
+		log.Fatalf("challenge.TestcaseFromJSON: %s", err.Error())
 	}
 
-	pt := hefloat.NewPlaintext(params.Scheme, params.Scheme.MaxLevel())
+	ch, err := lit.Challenge()
+	if err != nil {
+		log.Fatalf("challenge.Literal.Challenge: %s", err.Error())
+	}
 
+	if err := challenge.CheckParameters(ch, params.Scheme); err != nil {
+		log.Fatalf("challenge.CheckParameters: %s", err.Error())
+	}
 
-	if err = ecd.Encode(values, pt); err != nil {
-		log.Fatalf(err.Error())
+	values, err := ch.GenInput(params.Scheme, lit.Rand())
+	if err != nil {
+		log.Fatalf("challenge.Challenge.GenInput: %s", err.Error())
 	}
 
-	input, err := enc.EncryptNew(pt)
+	slots := ch.Layout(params.Scheme).Slots
 
-	if err != nil {
-		log.Fatalf(err.Error())
+	if params.Scheme.RingType() == ring.ConjugateInvariant {
+		log.Printf("ring %s: inputs are encoded in %d real slots", params.Scheme.RingType(), params.Scheme.MaxSlots())
+	}
+
+	input := utils.Ciphertexts{}
+	for _, name := range values.Names() {
+		if input[name], err = utils.EncryptVector(params.Scheme, ecd, enc, values[name], slots, params.Scheme.MaxLevel()); err != nil {
+			log.Fatalf("%s: utils.EncryptVector: %s", name, err.Error())
+		}
+		log.Printf("input %s: %d values in %d ciphertext(s)", name, len(values[name]), len(input[name]))
 	}
 
-	if err := utils.Serialize(params, *ccFile); err != nil {
+	if err := utils.Serialize(params, *ccFile, nil); err != nil {
 		log.Fatalf(err.Error())
 	}
 
-	if err := utils.Serialize(sk, *skFile); err != nil {
+	if err := utils.Serialize(sk, *skFile, &params); err != nil {
 		log.Fatalf(err.Error())
 	}
 
-	if err := utils.Serialize(input, *inputFile); err != nil {
+	if err := utils.Serialize(input, *inputFile, &params); err != nil {
 		log.Fatalf(err.Error())
 	}
 
@@ -72,7 +100,7 @@ func main() {
 		log.Fatalf(err.Error())
 	}
 
-	if err := utils.Serialize(evk, *evkFile); err != nil {
+	if err := utils.Serialize(evk, *evkFile, &params); err != nil {
 		log.Fatalf(err.Error())
 	}
 }
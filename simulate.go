@@ -0,0 +1,112 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"app/internal/challenge"
+	"app/internal/cleartext"
+	"app/internal/report"
+	"app/internal/simulator"
+	"app/internal/solution"
+	"app/utils"
+)
+
+// Runs the solution on cleartext values with the simulated evaluator, and the
+// simulated bootstrapper if the bootstrapping is enabled, and prints the level,
+// scale and degree after each operation, the depth consumed and the precision
+// of the algorithm without encryption noise.
+func main() {
+	configFile := flag.String("config", "config.json", "")
+	quiet := flag.Bool("quiet", false, "do not print the operations")
+
+	flag.Parse()
+
+	dataJSON, err := os.ReadFile(*configFile)
+	if err != nil {
+		log.Fatalf("os.ReadFile(%s): %s", *configFile, err.Error())
+	}
+
+	params := utils.Parameters{}
+	if err := params.UnmarshalJSON(dataJSON); err != nil {
+		log.Fatalf("utils.Parameters.UnmarshalJSON: %s", err.Error())
+	}
+
+	lit, err := challenge.LiteralFromJSON(dataJSON)
+	if err != nil {
+		log.Fatalf("challenge.LiteralFromJSON: %s", err.Error())
+	}
+
+	ch, err := lit.Challenge()
+	if err != nil {
+		log.Fatalf("challenge.Literal.Challenge: %s", err.Error())
+	}
+
+	if err := challenge.CheckParameters(ch, params.Scheme); err != nil {
+		log.Fatalf("challenge.CheckParameters: %s", err.Error())
+	}
+
+	values, err := ch.GenInput(params.Scheme, lit.Rand())
+	if err != nil {
+		log.Fatalf("challenge.Challenge.GenInput: %s", err.Error())
+	}
+
+	eval := simulator.NewEvaluator(params.Scheme)
+
+	slots := ch.Layout(params.Scheme).Slots
+
+	in := utils.Ciphertexts{}
+	for _, name := range values.Names() {
+		for _, chunk := range utils.SplitSlots(values[name], slots) {
+			in[name] = append(in[name], eval.NewCiphertext(chunk, params.Scheme.MaxLevel()))
+		}
+	}
+
+	out, err := solution.SolveTestcase(context.Background(), params, utils.EvaluationKeySet{}, eval, in)
+	if err != nil {
+		log.Fatalf("solution.SolveTestcase: %s", err.Error())
+	}
+
+	if !*quiet {
+		for i, op := range eval.Trace {
+			fmt.Printf("%4d %-16s level=%2d logscale=%6.2f degree=%d\n", i, op.Name, op.Level, op.LogScale, op.Degree)
+		}
+	}
+
+	want := ch.Reference(values)
+
+	fmt.Printf("Operations: %d\n", len(eval.Trace))
+	fmt.Printf("Rescales  : %d\n", eval.Count("Rescale"))
+	fmt.Printf("Depth     : %d (level %d -> %d)\n", eval.Depth(), params.Scheme.MaxLevel(), eval.MinLevel())
+
+	if params.Bootstrapping != nil {
+		fmt.Printf("Bootstraps: %d\n", eval.Count("Bootstrap"))
+	}
+
+	prec := float64(report.MaxPrecision)
+
+	for _, name := range want.Names() {
+
+		v, err := out.Get(name)
+		if err != nil {
+			log.Fatalf("missing output: %s", err.Error())
+		}
+
+		var have []complex128
+		for _, ct := range v {
+			fmt.Printf("Output    : %s level=%d logscale=%.2f degree=%d\n", name, ct.Level(), ct.LogScale(), ct.Degree())
+			have = append(have, eval.Values(ct)[:slots]...)
+		}
+
+		if len(have) < len(want[name]) {
+			log.Fatalf("output %s: %d values, expected %d", name, len(have), len(want[name]))
+		}
+
+		prec = min(prec, cleartext.Precision(have, want[name]))
+	}
+
+	fmt.Printf("Precision : %.2f bits (threshold %.2f bits, without encryption noise)\n", prec, ch.Threshold())
+}
@@ -0,0 +1,194 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"app/internal/challenge"
+	"app/internal/report"
+)
+
+// Runs setup.go, main.go and verify.go on each testcase of the "Suite" block
+// of config.json, in its own folder, and aggregates their reports.
+func main() {
+	dir := flag.String("dir", "temps", "folder of the testcases, each written to <dir>/case_<i>")
+	reportFile := flag.String("report", "temps/suite.json", "file to write the JSON summary of the suite to")
+	testcases := flag.Int("cases", 0, "number of testcases, overrides Suite.Testcases if positive")
+	minPrecision := flag.Float64("min-prec", -1, "minimum precision in bits, overrides the challenge threshold if non-negative")
+
+	flag.Parse()
+
+	dataJSON, err := os.ReadFile("config.json")
+	if err != nil {
+		log.Fatalf("os.ReadFile(%s): %s", "config.json", err.Error())
+	}
+
+	lit, err := challenge.LiteralFromJSON(dataJSON)
+	if err != nil {
+		log.Fatalf("challenge.LiteralFromJSON: %s", err.Error())
+	}
+
+	suite, err := challenge.SuiteLiteralFromJSON(dataJSON)
+	if err != nil {
+		log.Fatalf("challenge.SuiteLiteralFromJSON: %s", err.Error())
+	}
+
+	if *testcases > 0 {
+		suite.Testcases = *testcases
+	}
+
+	if *minPrecision >= 0 {
+		lit.MinPrecision = minPrecision
+	}
+
+	ch, err := lit.Challenge()
+	if err != nil {
+		log.Fatalf("challenge.Literal.Challenge: %s", err.Error())
+	}
+
+	// The programs are built once instead of being compiled by go run for every testcase
+	bin := filepath.Join(*dir, "bin")
+	for _, prog := range []string{"setup", "main", "verify"} {
+		if err := run("", "go", "build", "-o", filepath.Join(bin, prog), prog+".go"); err != nil {
+			log.Fatalf("go build %s.go: %s", prog, err.Error())
+		}
+	}
+
+	results := make([]report.Testcase, suite.Testcases)
+
+	for i := range results {
+
+		tc, err := suite.Testcase(lit, i)
+		if err != nil {
+			log.Fatalf("challenge.SuiteLiteral.Testcase: %s", err.Error())
+		}
+
+		results[i] = runTestcase(bin, filepath.Join(*dir, fmt.Sprintf("case_%d", i)), i, *minPrecision)
+		results[i].Seed = tc.Seed
+
+		switch {
+		case results[i].Error != "":
+			fmt.Printf("case %d (seed %d): ERROR (%s)\n", i, tc.Seed, results[i].Error)
+		default:
+			verdict := "FAIL"
+			if results[i].Passed {
+				verdict = "PASS"
+			}
+			fmt.Printf("case %d (seed %d): %s (min precision %.2f bits, runtime %s)\n", i, tc.Seed, verdict, results[i].Precision, results[i].Runtime)
+		}
+	}
+
+	s := report.NewSuite(lit.Name, ch.Threshold(), results)
+
+	if *reportFile != "" {
+		if err := s.WriteFile(*reportFile); err != nil {
+			log.Fatalf("report.Suite.WriteFile: %s", err.Error())
+		}
+	}
+
+	fmt.Println(s.String())
+
+	if !s.Passed {
+		os.Exit(1)
+	}
+}
+
+// runTestcase generates the i-th testcase in dir, runs the solution on it and verifies its output.
+// The output of the programs is written to dir/log.txt.
+func runTestcase(bin, dir string, i int, minPrecision float64) (tc report.Testcase) {
+
+	tc.Index = i
+
+	path := func(name string) string {
+		return filepath.Join(dir, name)
+	}
+
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		tc.Error = err.Error()
+		return
+	}
+
+	// A report left by a previous run must not be taken for the one of this run
+	if err := os.Remove(path("report.json")); err != nil && !os.IsNotExist(err) {
+		tc.Error = err.Error()
+		return
+	}
+
+	logFile := path("log.txt")
+	if err := os.WriteFile(logFile, nil, 0600); err != nil {
+		tc.Error = err.Error()
+		return
+	}
+
+	testcase := "--case=" + strconv.Itoa(i)
+
+	if err := run(logFile, filepath.Join(bin, "setup"), testcase,
+		"--sk="+path("sk.bin"), "--cc="+path("cc.bin"), "--key_eval="+path("evalkey.bin"), "--input="+path("in.bin")); err != nil {
+		tc.Error = "setup: " + err.Error()
+		return
+	}
+
+	if err := run(logFile, filepath.Join(bin, "main"),
+		"--cc="+path("cc.bin"), "--key_eval="+path("evalkey.bin"), "--input="+path("in.bin"), "--output="+path("out.bin"), "--runtime="+path("runtime.txt")); err != nil {
+		tc.Error = "solution: " + err.Error()
+		return
+	}
+
+	// verify exits with an error if the testcase fails, after writing its report
+	err := run(logFile, filepath.Join(bin, "verify"), testcase, "--min-prec="+strconv.FormatFloat(minPrecision, 'g', -1, 64),
+		"--sk="+path("sk.bin"), "--cc="+path("cc.bin"), "--output="+path("out.bin"), "--runtime="+path("runtime.txt"), "--report="+path("report.json"))
+
+	rep, rerr := report.ReadFile(path("report.json"))
+	if rerr != nil {
+		if err == nil {
+			err = rerr
+		}
+		tc.Error = "verify: " + err.Error()
+		return
+	}
+
+	tc.Passed = rep.Passed
+	tc.Precision = rep.Precision.Min.L2
+	tc.Runtime = rep.Runtime
+
+	return
+}
+
+// run runs the command and appends its output to logFile, or discards it if logFile is empty.
+// The error includes the last line of the output.
+func run(logFile, name string, args ...string) (err error) {
+
+	/* #nosec G204 */
+	cmd := exec.Command(name, args...)
+
+	out, err := cmd.CombinedOutput()
+
+	if logFile != "" {
+		f, ferr := os.OpenFile(logFile, os.O_APPEND|os.O_WRONLY, 0600)
+		if ferr != nil {
+			return ferr
+		}
+		defer f.Close()
+
+		if _, ferr = fmt.Fprintf(f, "$ %s %s\n%s\n", name, strings.Join(args, " "), out); ferr != nil {
+			return ferr
+		}
+	}
+
+	if err != nil {
+		var exitErr *exec.ExitError
+		if lines := strings.Split(strings.TrimSpace(string(out)), "\n"); errors.As(err, &exitErr) && lines[len(lines)-1] != "" {
+			return fmt.Errorf("%w: %s", err, lines[len(lines)-1])
+		}
+		return err
+	}
+
+	return
+}
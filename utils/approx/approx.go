@@ -0,0 +1,178 @@
+// Package approx computes polynomial approximations of non-polynomial
+// functions, either Chebyshev interpolants or minimax (Remez) polynomials,
+// and evaluates them on ciphertexts with hefloat.PolynomialEvaluator.
+// Functions with a discontinuity, such as the sign, are better approximated
+// by composite polynomials (see composite.go).
+package approx
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+	"math/bits"
+
+	"github.com/tuneinsight/lattigo/v5/utils/bignum"
+)
+
+// Prec is the precision, in bits, of the coefficients of the approximations.
+const Prec = 128
+
+// Approximation is a polynomial approximation of a function on an interval.
+type Approximation struct {
+	Polynomial bignum.Polynomial // In the Chebyshev basis of the interval.
+	MaxError   float64           // Maximum absolute error over the interval, measured on a dense grid.
+}
+
+// Degree returns the degree of the polynomial.
+func (a Approximation) Degree() int {
+	return a.Polynomial.Degree()
+}
+
+// Depth returns the number of levels consumed by Evaluator.Evaluate: one for
+// the change of basis of the interval to [-1, 1], unless 2/(b-a) is an integer,
+// and ceil(log2(degree+1)) for the Paterson–Stockmeyer evaluation.
+func (a Approximation) Depth() (depth int) {
+	if !isUnitInterval(a.Polynomial) {
+		if scalar, _ := a.Polynomial.ChangeOfBasis(); !scalar.IsInt() {
+			depth++
+		}
+	}
+	return depth + bits.Len(uint(a.Degree()))
+}
+
+// LogError returns the base two logarithm of MaxError, that is minus the precision in bits.
+func (a Approximation) LogError() float64 {
+	return math.Log2(a.MaxError)
+}
+
+// Evaluate returns the value of the polynomial at x, in cleartext.
+func (a Approximation) Evaluate(x float64) float64 {
+	y, _ := a.Polynomial.Evaluate(x)[0].Float64()
+	return y
+}
+
+// Chebyshev returns the interpolant of f at the degree+1 Chebyshev nodes of [a, b].
+func Chebyshev(f func(x float64) float64, a, b float64, degree int) (Approximation, error) {
+
+	if err := checkInterval(a, b, degree); err != nil {
+		return Approximation{}, fmt.Errorf("cannot Chebyshev: %w", err)
+	}
+
+	interval := bignum.Interval{
+		Nodes: degree,
+		A:     *bignum.NewFloat(a, Prec),
+		B:     *bignum.NewFloat(b, Prec),
+	}
+
+	p := bignum.ChebyshevApproximation(f, interval)
+
+	return newApproximation(f, p), nil
+}
+
+// Minimax returns the polynomial of the given degree minimizing the maximum
+// absolute error to f over [a, b], computed with the Remez exchange algorithm.
+// Unlike bignum.Remez, it supports a single interval and prints nothing.
+func Minimax(f func(x float64) float64, a, b float64, degree int) (Approximation, error) {
+
+	if err := checkInterval(a, b, degree); err != nil {
+		return Approximation{}, fmt.Errorf("cannot Minimax: %w", err)
+	}
+
+	coeffs, err := remez(f, a, b, degree)
+	if err != nil {
+		return Approximation{}, fmt.Errorf("cannot Minimax: %w", err)
+	}
+
+	return newApproximation(f, bignum.NewPolynomial(bignum.Chebyshev, coeffs, [2]float64{a, b})), nil
+}
+
+func checkInterval(a, b float64, degree int) error {
+
+	if !(a < b) {
+		return fmt.Errorf("invalid interval [%v, %v]", a, b)
+	}
+
+	if degree < 1 {
+		return fmt.Errorf("invalid degree %d, must be at least 1", degree)
+	}
+
+	return nil
+}
+
+// gridSize is the number of points of the grid on which the error of an
+// approximation is measured, per coefficient.
+const gridSize = 32
+
+// newApproximation returns the approximation p of f, with its error measured on a grid of [p.A, p.B].
+func newApproximation(f func(x float64) float64, p bignum.Polynomial) (a Approximation) {
+
+	a.Polynomial = p
+
+	lo, _ := p.A.Float64()
+	hi, _ := p.B.Float64()
+
+	coeffs := make([]float64, len(p.Coeffs))
+	for i, c := range p.Coeffs {
+		if c != nil {
+			coeffs[i], _ = c[0].Float64()
+		}
+	}
+
+	for _, t := range grid(gridSize * len(coeffs)) {
+		a.MaxError = math.Max(a.MaxError, math.Abs(clenshaw(coeffs, t)-f(fromUnit(t, lo, hi))))
+	}
+
+	return
+}
+
+// withParity returns p with its coefficients of the other parity set to zero,
+// which lets hefloat.PolynomialEvaluator skip them, if p is odd or even on an
+// interval symmetric around zero.
+func withParity(p bignum.Polynomial, odd, even bool) bignum.Polynomial {
+
+	if odd == even || p.A.Cmp(new(big.Float).Neg(&p.B)) != 0 {
+		return p
+	}
+
+	for i := range p.Coeffs {
+		if (i&1 == 1) != odd {
+			p.Coeffs[i] = bignum.NewComplex().SetPrec(Prec)
+		}
+	}
+
+	p.IsOdd, p.IsEven = odd, even
+
+	return p
+}
+
+// isUnitInterval returns true if the interval of p is [-1, 1], in which case no change of basis is needed.
+func isUnitInterval(p bignum.Polynomial) bool {
+	return p.Basis == bignum.Monomial || (p.A.Cmp(big.NewFloat(-1)) == 0 && p.B.Cmp(big.NewFloat(1)) == 0)
+}
+
+// toUnit maps x from [a, b] to [-1, 1], and fromUnit maps it back.
+func toUnit(x, a, b float64) float64 {
+	return (2*x - a - b) / (b - a)
+}
+
+func fromUnit(t, a, b float64) float64 {
+	return ((b-a)*t + a + b) / 2
+}
+
+// grid returns n points of [-1, 1], denser near the ends like the Chebyshev nodes.
+func grid(n int) (t []float64) {
+	t = make([]float64, n)
+	for i := range t {
+		t[i] = -math.Cos(math.Pi * float64(i) / float64(n-1))
+	}
+	return
+}
+
+// clenshaw returns sum c[k] T_k(t).
+func clenshaw(c []float64, t float64) float64 {
+	var b1, b2 float64
+	for k := len(c) - 1; k >= 1; k-- {
+		b1, b2 = 2*t*b1-b2+c[k], b1
+	}
+	return t*b1 - b2 + c[0]
+}
@@ -0,0 +1,86 @@
+package approx
+
+import (
+	"fmt"
+	"math/bits"
+
+	"github.com/tuneinsight/lattigo/v5/he/hefloat"
+	"github.com/tuneinsight/lattigo/v5/utils/bignum"
+)
+
+// A composite polynomial p_k o ... o p_1 o p_0 of the sign approximates it
+// on [-1, -2^-alpha] U [2^-alpha, 1] with a much lower depth than a single
+// polynomial of the same precision. The polynomials are in the Chebyshev
+// basis of [-1, 1], as expected by Evaluator.Composite.
+
+// DefaultCompositeSign returns the composite polynomial of hefloat.NewComparisonEvaluator,
+// which distinguishes values at distance 2^-30 of zero with a precision of about 80 bits,
+// up to the scheme precision. It consumes 40 levels, and thus needs bootstrapping.
+func DefaultCompositeSign() hefloat.MinimaxCompositePolynomial {
+	return hefloat.NewMinimaxCompositePolynomial(hefloat.DefaultMinimaxCompositePolynomialForSign)
+}
+
+// IteratedSign returns the composition of n times 35/16x - 35/16x^3 + 21/16x^5 - 5/16x^7,
+// which pushes the values of [-1, 1] towards their sign, 3 levels per iteration.
+// A value of magnitude 2^-alpha needs about 0.9*alpha iterations to get close to its sign,
+// after which every iteration about quadruples the number of correct bits.
+func IteratedSign(n int) hefloat.MinimaxCompositePolynomial {
+	coeffs := make([][]string, n)
+	for i := range coeffs {
+		coeffs[i] = hefloat.CoeffsSignX4Cheby
+	}
+	return hefloat.NewMinimaxCompositePolynomial(coeffs)
+}
+
+// GenCompositeSign returns the minimax composite polynomial of the sign on
+// [-1, -2^-logAlpha] U [2^-logAlpha, 1], tolerating a scheme error of 2^-logErr,
+// whose polynomials have the given degrees, followed by one iteration of IteratedSign.
+// It wraps hefloat.GenMinimaxCompositePolynomial, which prints its progress and can take
+// minutes: the result is meant to be generated once and hard-coded with CompositeCoefficients.
+func GenCompositeSign(logAlpha, logErr int, degrees []int) (hefloat.MinimaxCompositePolynomial, error) {
+
+	if len(degrees) == 0 {
+		return nil, fmt.Errorf("cannot GenCompositeSign: no degree")
+	}
+
+	for _, d := range degrees {
+		if d < 1 || d&1 == 0 {
+			return nil, fmt.Errorf("cannot GenCompositeSign: invalid degree %d, the degrees must be odd", d)
+		}
+	}
+
+	coeffs := hefloat.GenMinimaxCompositePolynomial(256, logAlpha, logErr, degrees, bignum.Sign)
+
+	mcp := make(hefloat.MinimaxCompositePolynomial, len(coeffs), len(coeffs)+1)
+	for i := range coeffs {
+		mcp[i] = bignum.NewPolynomial(bignum.Chebyshev, coeffs[i], [2]float64{-1, 1})
+		mcp[i].IsOdd, mcp[i].IsEven = true, false
+	}
+
+	return append(mcp, IteratedSign(1)...), nil
+}
+
+// CompositeCoefficients returns the coefficients of a composite polynomial in the format
+// of hefloat.NewMinimaxCompositePolynomial, to hard-code the result of GenCompositeSign.
+func CompositeCoefficients(mcp hefloat.MinimaxCompositePolynomial) (coeffs [][]string) {
+	coeffs = make([][]string, len(mcp))
+	for i, p := range mcp {
+		coeffs[i] = make([]string, len(p.Coeffs))
+		for j, c := range p.Coeffs {
+			if c == nil {
+				coeffs[i][j] = "0"
+			} else {
+				coeffs[i][j] = c[0].Text('g', 30)
+			}
+		}
+	}
+	return
+}
+
+// CompositeDepth returns the number of levels consumed by the evaluation of a composite polynomial.
+func CompositeDepth(mcp hefloat.MinimaxCompositePolynomial) (depth int) {
+	for _, p := range mcp {
+		depth += bits.Len(uint(p.Degree()))
+	}
+	return
+}
@@ -0,0 +1,210 @@
+package approx
+
+import (
+	"fmt"
+	"math/big"
+	"math/bits"
+
+	"github.com/tuneinsight/lattigo/v5/core/rlwe"
+	"github.com/tuneinsight/lattigo/v5/he"
+	"github.com/tuneinsight/lattigo/v5/he/hefloat"
+	"github.com/tuneinsight/lattigo/v5/ring"
+	"github.com/tuneinsight/lattigo/v5/utils/bignum"
+
+	"app/utils"
+)
+
+// Evaluator evaluates approximations and composite polynomials on ciphertexts.
+type Evaluator struct {
+	params hefloat.Parameters
+	eval   utils.Evaluator
+	btp    he.Bootstrapper[rlwe.Ciphertext]
+	poly   *hefloat.PolynomialEvaluator
+}
+
+// NewEvaluator returns an Evaluator on top of the evaluator given to SolveTestcase.
+// The bootstrapper, which can be nil, is only used by the composite polynomials,
+// between two polynomials when the level is too low for the next one, and is
+// wrapped by eval (see utils.WrapBootstrapper).
+// With ring.Standard, the composite polynomials take the real part of the
+// intermediate values, which needs the Galois key of the complex conjugation.
+func NewEvaluator(params hefloat.Parameters, eval utils.Evaluator, btp he.Bootstrapper[rlwe.Ciphertext]) *Evaluator {
+	return &Evaluator{
+		params: params,
+		eval:   eval,
+		btp:    utils.WrapBootstrapper(eval, btp),
+		poly:   hefloat.NewPolynomialEvaluator(params, eval),
+	}
+}
+
+// Evaluate returns the approximation evaluated on ct, whose values must be in its
+// interval, at the default scale. It consumes Approximation.Depth levels.
+func (e Evaluator) Evaluate(ct *rlwe.Ciphertext, a Approximation) (res *rlwe.Ciphertext, err error) {
+
+	defer utils.Begin(e.eval, "Polynomial")()
+
+	if ct.Level() < a.Depth() {
+		return nil, fmt.Errorf("cannot Evaluate: the approximation of degree %d needs %d levels but the ciphertext is at level %d", a.Degree(), a.Depth(), ct.Level())
+	}
+
+	// Maps the interval of the approximation to [-1, 1]
+	if !isUnitInterval(a.Polynomial) {
+
+		scalar, constant := a.Polynomial.ChangeOfBasis()
+
+		if res, err = e.eval.MulNew(ct, scalar); err != nil {
+			return nil, fmt.Errorf("%T.MulNew: %w", e.eval, err)
+		}
+
+		if err = e.eval.Add(res, constant, res); err != nil {
+			return nil, fmt.Errorf("%T.Add: %w", e.eval, err)
+		}
+
+		// A multiplication by an integer scalar does not change the scale
+		if !scalar.IsInt() {
+			if err = e.eval.Rescale(res, res); err != nil {
+				return nil, fmt.Errorf("%T.Rescale: %w", e.eval, err)
+			}
+		}
+
+		ct = res
+	}
+
+	if res, err = e.poly.Evaluate(ct, a.Polynomial, e.params.DefaultScale()); err != nil {
+		return nil, fmt.Errorf("%T.Evaluate: %w", e.poly, err)
+	}
+
+	return
+}
+
+// Composite returns the composite polynomial evaluated on ct, whose values must be in [-1, 1].
+// It consumes CompositeDepth levels, bootstrapping in between if needed and possible.
+func (e Evaluator) Composite(ct *rlwe.Ciphertext, mcp hefloat.MinimaxCompositePolynomial) (res *rlwe.Ciphertext, err error) {
+
+	defer utils.Begin(e.eval, "Composite")()
+
+	res = ct
+
+	for i, p := range mcp {
+
+		if depth := bits.Len(uint(p.Degree())); res.Level() < depth {
+
+			if e.btp == nil {
+				return nil, fmt.Errorf("cannot Composite: polynomial %d needs %d levels but the ciphertext is at level %d and there is no bootstrapper", i, depth, res.Level())
+			}
+
+			if res, err = e.btp.Bootstrap(res); err != nil {
+				return nil, fmt.Errorf("cannot Composite: %T.Bootstrap: %w", e.btp, err)
+			}
+		}
+
+		// With complex slots, p/2 is evaluated and added to its conjugate, which
+		// removes the imaginary part that the composition would otherwise amplify
+		if e.params.RingType() == ring.Standard {
+			p = scale(p, 0.5, 0)
+		}
+
+		end := utils.Begin(e.eval, "Polynomial")
+		res, err = e.poly.Evaluate(res, p, e.params.DefaultScale())
+		end()
+
+		if err != nil {
+			return nil, fmt.Errorf("cannot Composite: polynomial %d: %T.Evaluate: %w", i, e.poly, err)
+		}
+
+		if e.params.RingType() == ring.Standard {
+
+			var conj *rlwe.Ciphertext
+			if conj, err = e.eval.ConjugateNew(res); err != nil {
+				return nil, fmt.Errorf("cannot Composite: %T.ConjugateNew: %w", e.eval, err)
+			}
+
+			if err = e.eval.Add(res, conj, res); err != nil {
+				return nil, fmt.Errorf("cannot Composite: %T.Add: %w", e.eval, err)
+			}
+		}
+	}
+
+	return
+}
+
+// Sign returns the sign of the values of ct, in [-1, 1], with the composite polynomial of the sign mcp.
+func (e Evaluator) Sign(ct *rlwe.Ciphertext, mcp hefloat.MinimaxCompositePolynomial) (res *rlwe.Ciphertext, err error) {
+	return e.Composite(ct, mcp)
+}
+
+// Step returns (sign(x)+1)/2 for the values x of ct, in [-1, 1], with the composite polynomial of the sign mcp.
+// It consumes as many levels as Sign.
+func (e Evaluator) Step(ct *rlwe.Ciphertext, mcp hefloat.MinimaxCompositePolynomial) (res *rlwe.Ciphertext, err error) {
+
+	if len(mcp) == 0 {
+		return nil, fmt.Errorf("cannot Step: empty composite polynomial")
+	}
+
+	step := append(hefloat.MinimaxCompositePolynomial{}, mcp...)
+	step[len(step)-1] = scale(step[len(step)-1], 0.5, 0.5)
+
+	return e.Composite(ct, step)
+}
+
+// Compare returns Step(a-b): 1 if a > b, 0 if a < b and 0.5 if a = b, where a-b must be in [-1, 1].
+func (e Evaluator) Compare(a, b *rlwe.Ciphertext, mcp hefloat.MinimaxCompositePolynomial) (res *rlwe.Ciphertext, err error) {
+
+	var diff *rlwe.Ciphertext
+	if diff, err = e.eval.SubNew(a, b); err != nil {
+		return nil, fmt.Errorf("%T.SubNew: %w", e.eval, err)
+	}
+
+	return e.Step(diff, mcp)
+}
+
+// ReLU returns x*Step(x) for the values x of ct, in [-1, 1], with the composite polynomial of the sign mcp.
+// It consumes one more level than Sign.
+func (e Evaluator) ReLU(ct *rlwe.Ciphertext, mcp hefloat.MinimaxCompositePolynomial) (res *rlwe.Ciphertext, err error) {
+
+	if res, err = e.Step(ct, mcp); err != nil {
+		return
+	}
+
+	x := ct
+	if ct.Level() > res.Level() {
+		x = ct.CopyNew()
+		e.eval.DropLevel(x, ct.Level()-res.Level())
+	}
+
+	if err = e.eval.MulRelin(res, x, res); err != nil {
+		return nil, fmt.Errorf("%T.MulRelin: %w", e.eval, err)
+	}
+
+	if err = e.eval.Rescale(res, res); err != nil {
+		return nil, fmt.Errorf("%T.Rescale: %w", e.eval, err)
+	}
+
+	return
+}
+
+// scale returns a*p+b.
+func scale(p bignum.Polynomial, a, b float64) bignum.Polynomial {
+
+	p = p.Clone()
+
+	for i := range p.Coeffs {
+		if p.Coeffs[i] != nil {
+			p.Coeffs[i][0].Mul(p.Coeffs[i][0], new(big.Float).SetFloat64(a))
+			p.Coeffs[i][1].Mul(p.Coeffs[i][1], new(big.Float).SetFloat64(a))
+		}
+	}
+
+	if p.Coeffs[0] == nil {
+		p.Coeffs[0] = bignum.NewComplex().SetPrec(Prec)
+	}
+
+	p.Coeffs[0][0].Add(p.Coeffs[0][0], new(big.Float).SetFloat64(b))
+
+	// A non-zero constant term breaks the parity
+	if b != 0 {
+		p.IsOdd, p.IsEven = true, true
+	}
+
+	return p
+}
@@ -0,0 +1,107 @@
+package approx
+
+import (
+	"fmt"
+	"math"
+	"sort"
+)
+
+// Function is a real function along with the interval on which it is approximated by default.
+type Function struct {
+	Name      string
+	F         func(x float64) float64
+	A, B      float64 // Default interval.
+	Odd, Even bool    // Parity of F, used to skip the zero coefficients on intervals symmetric around zero.
+}
+
+var (
+	// Sign is 1 if x > 0, -1 if x < 0 and 0 if x = 0. A single polynomial
+	// converges slowly around 0, see the composite polynomials of composite.go.
+	Sign = Function{Name: "sign", F: sign, A: -1, B: 1, Odd: true}
+
+	// Step is 1 if x > 0, 0 if x < 0 and 0.5 if x = 0, that is the comparison
+	// of a and b as Step(a-b).
+	Step = Function{Name: "step", F: func(x float64) float64 { return (sign(x) + 1) / 2 }, A: -1, B: 1}
+
+	// ReLU is max(x, 0).
+	ReLU = Function{Name: "relu", F: func(x float64) float64 { return math.Max(x, 0) }, A: -1, B: 1}
+
+	// Inverse is 1/x.
+	Inverse = Function{Name: "inverse", F: func(x float64) float64 { return 1 / x }, A: 1.0 / 64, B: 1}
+
+	// Sqrt is the square root.
+	Sqrt = Function{Name: "sqrt", F: math.Sqrt, A: 0, B: 1}
+
+	// Exp is the exponential.
+	Exp = Function{Name: "exp", F: math.Exp, A: -1, B: 1}
+
+	// Log is the natural logarithm.
+	Log = Function{Name: "log", F: math.Log, A: 1.0 / 64, B: 1}
+
+	// Sigmoid is 1/(1+exp(-x)).
+	Sigmoid = Function{Name: "sigmoid", F: func(x float64) float64 { return 1 / (1 + math.Exp(-x)) }, A: -8, B: 8}
+
+	// Tanh is the hyperbolic tangent.
+	Tanh = Function{Name: "tanh", F: math.Tanh, A: -4, B: 4, Odd: true}
+)
+
+var functions = map[string]Function{}
+
+func init() {
+	for _, f := range []Function{Sign, Step, ReLU, Inverse, Sqrt, Exp, Log, Sigmoid, Tanh} {
+		functions[f.Name] = f
+	}
+}
+
+// Names returns the sorted names of the functions of the package.
+func Names() (names []string) {
+	for name := range functions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return
+}
+
+// Get returns the function of the package with the given name.
+func Get(name string) (Function, error) {
+	f, ok := functions[name]
+	if !ok {
+		return Function{}, fmt.Errorf("unknown function %q, available functions are %v", name, Names())
+	}
+	return f, nil
+}
+
+// On returns the function with the interval [a, b].
+func (f Function) On(a, b float64) Function {
+	f.A, f.B = a, b
+	return f
+}
+
+// Chebyshev returns the Chebyshev interpolant of the given degree of f over [A, B].
+func (f Function) Chebyshev(degree int) (a Approximation, err error) {
+	if a, err = Chebyshev(f.F, f.A, f.B, degree); err != nil {
+		return a, fmt.Errorf("%s: %w", f.Name, err)
+	}
+	a.Polynomial = withParity(a.Polynomial, f.Odd, f.Even)
+	return
+}
+
+// Minimax returns the minimax polynomial of the given degree of f over [A, B].
+func (f Function) Minimax(degree int) (a Approximation, err error) {
+	if a, err = Minimax(f.F, f.A, f.B, degree); err != nil {
+		return a, fmt.Errorf("%s: %w", f.Name, err)
+	}
+	a.Polynomial = withParity(a.Polynomial, f.Odd, f.Even)
+	return
+}
+
+func sign(x float64) float64 {
+	switch {
+	case x > 0:
+		return 1
+	case x < 0:
+		return -1
+	default:
+		return 0
+	}
+}
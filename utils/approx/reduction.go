@@ -0,0 +1,117 @@
+package approx
+
+import (
+	"fmt"
+
+	"github.com/tuneinsight/lattigo/v5/core/rlwe"
+	"github.com/tuneinsight/lattigo/v5/he/hefloat"
+
+	"app/utils"
+)
+
+// Max returns the maximum of the values reduced by r, computed as
+// max(a, b) = b + ReLU(a-b) with the composite polynomial of the sign mcp:
+// the values must lie in an interval of length at most 1. Every one of the
+// about log2(r.N) sequential comparisons consumes CompositeDepth(mcp)+1 levels.
+func (e Evaluator) Max(ct *rlwe.Ciphertext, r utils.Reduction, mcp hefloat.MinimaxCompositePolynomial) (res *rlwe.Ciphertext, err error) {
+	return r.Reduce(e.eval, ct, func(a, b *rlwe.Ciphertext) (*rlwe.Ciphertext, error) {
+		return e.max(a, b, mcp, false)
+	})
+}
+
+// Min returns the minimum of the values reduced by r, computed as
+// min(a, b) = a - ReLU(a-b), see Max.
+func (e Evaluator) Min(ct *rlwe.Ciphertext, r utils.Reduction, mcp hefloat.MinimaxCompositePolynomial) (res *rlwe.Ciphertext, err error) {
+	return r.Reduce(e.eval, ct, func(a, b *rlwe.Ciphertext) (*rlwe.Ciphertext, error) {
+		return e.max(a, b, mcp, true)
+	})
+}
+
+// ArgMax returns 1 in the slots holding the maximum of the values reduced by r
+// and 0 elsewhere, as 1 - Sign(max - x), which needs the maximum in every slot:
+// the reduction must span all the slots or set Broadcast. Several slots are
+// set on a tie, and the values closer to the maximum than the precision of
+// mcp are partially set.
+func (e Evaluator) ArgMax(ct *rlwe.Ciphertext, r utils.Reduction, mcp hefloat.MinimaxCompositePolynomial) (res *rlwe.Ciphertext, err error) {
+	return e.arg(ct, r, mcp, false)
+}
+
+// ArgMin returns 1 in the slots holding the minimum of the values reduced by r
+// and 0 elsewhere, see ArgMax.
+func (e Evaluator) ArgMin(ct *rlwe.Ciphertext, r utils.Reduction, mcp hefloat.MinimaxCompositePolynomial) (res *rlwe.Ciphertext, err error) {
+	return e.arg(ct, r, mcp, true)
+}
+
+// max returns max(a, b), or min(a, b) if min is true.
+func (e Evaluator) max(a, b *rlwe.Ciphertext, mcp hefloat.MinimaxCompositePolynomial, min bool) (res *rlwe.Ciphertext, err error) {
+
+	var diff *rlwe.Ciphertext
+	if diff, err = e.eval.SubNew(a, b); err != nil {
+		return nil, fmt.Errorf("%T.SubNew: %w", e.eval, err)
+	}
+
+	if res, err = e.ReLU(diff, mcp); err != nil {
+		return
+	}
+
+	if min {
+		if res, err = e.eval.SubNew(a, res); err != nil {
+			return nil, fmt.Errorf("%T.SubNew: %w", e.eval, err)
+		}
+	} else {
+		if res, err = e.eval.AddNew(b, res); err != nil {
+			return nil, fmt.Errorf("%T.AddNew: %w", e.eval, err)
+		}
+	}
+
+	return
+}
+
+// arg returns ArgMax, or ArgMin if min is true.
+func (e Evaluator) arg(ct *rlwe.Ciphertext, r utils.Reduction, mcp hefloat.MinimaxCompositePolynomial, min bool) (res *rlwe.Ciphertext, err error) {
+
+	batch, n, err := r.Dimensions(e.params)
+	if err != nil {
+		return
+	}
+
+	if !r.Broadcast && n*batch != e.params.MaxSlots() {
+		return nil, fmt.Errorf("the reduction must set Broadcast when it does not span all the slots")
+	}
+
+	reduce := e.Max
+	if min {
+		reduce = e.Min
+	}
+
+	var m *rlwe.Ciphertext
+	if m, err = reduce(ct, r, mcp); err != nil {
+		return
+	}
+
+	// dist = max - x or x - min, in [0, 1]
+	a, b := m, ct
+	if min {
+		a, b = ct, m
+	}
+
+	var dist *rlwe.Ciphertext
+	if dist, err = e.eval.SubNew(a, b); err != nil {
+		return nil, fmt.Errorf("%T.SubNew: %w", e.eval, err)
+	}
+
+	if res, err = e.Sign(dist, mcp); err != nil {
+		return
+	}
+
+	// 1 - Sign(dist), the multiplication by -1 does not consume a level
+	if err = e.eval.Mul(res, -1, res); err != nil {
+		return nil, fmt.Errorf("%T.Mul: %w", e.eval, err)
+	}
+
+	if err = e.eval.Add(res, 1, res); err != nil {
+		return nil, fmt.Errorf("%T.Add: %w", e.eval, err)
+	}
+
+	return
+}
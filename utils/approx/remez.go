@@ -0,0 +1,153 @@
+package approx
+
+import (
+	"fmt"
+	"math"
+)
+
+const (
+	// remezMaxIterations bounds the number of exchanges of the Remez algorithm.
+	remezMaxIterations = 64
+
+	// remezThreshold is the relative spread between the largest and the smallest
+	// error at the reference points below which the Remez algorithm stops.
+	remezThreshold = 1e-6
+)
+
+// remez returns the coefficients, in the Chebyshev basis of [a, b], of the
+// minimax polynomial of the given degree of f over [a, b].
+func remez(f func(x float64) float64, a, b float64, degree int) (coeffs []float64, err error) {
+
+	n := degree + 2
+
+	// The first reference is the extrema of T_{degree+1}
+	ref := make([]float64, n)
+	for i := range ref {
+		ref[i] = -math.Cos(math.Pi * float64(i) / float64(n-1))
+	}
+
+	points := grid(gridSize * n)
+	values := make([]float64, len(points))
+	for i, t := range points {
+		values[i] = f(fromUnit(t, a, b))
+	}
+
+	best := math.Inf(1)
+
+	for iter := 0; iter < remezMaxIterations; iter++ {
+
+		var c []float64
+		if c, err = levelledSolve(f, a, b, ref); err != nil {
+			return
+		}
+
+		// Extremum of the error on each run of points where it has the same sign
+		var ext []float64
+		var extErr []float64
+		var maxErr float64
+		for i, t := range points {
+
+			e := clenshaw(c, t) - values[i]
+			maxErr = math.Max(maxErr, math.Abs(e))
+
+			switch k := len(ext) - 1; {
+			case k >= 0 && math.Signbit(e) == math.Signbit(extErr[k]):
+				if math.Abs(e) > math.Abs(extErr[k]) {
+					ext[k], extErr[k] = t, e
+				}
+			default:
+				ext, extErr = append(ext, t), append(extErr, e)
+			}
+		}
+
+		if maxErr < best {
+			best, coeffs = maxErr, c
+		}
+
+		// The error does not alternate enough to improve the reference
+		if len(ext) < n {
+			return
+		}
+
+		// Keeps n consecutive alternating extrema, dropping the smallest of the two ends
+		for len(ext) > n {
+			if math.Abs(extErr[0]) < math.Abs(extErr[len(ext)-1]) {
+				ext, extErr = ext[1:], extErr[1:]
+			} else {
+				ext, extErr = ext[:len(ext)-1], extErr[:len(ext)-1]
+			}
+		}
+
+		minErr := math.Inf(1)
+		for _, e := range extErr {
+			minErr = math.Min(minErr, math.Abs(e))
+		}
+
+		if maxErr == 0 || (maxErr-minErr)/maxErr < remezThreshold {
+			return
+		}
+
+		ref = ext
+	}
+
+	return
+}
+
+// levelledSolve returns the coefficients c, in the Chebyshev basis, such that
+// sum c[k] T_k(t_i) + (-1)^i E = f(x_i) at the reference points t_i of [-1, 1].
+func levelledSolve(f func(x float64) float64, a, b float64, ref []float64) (c []float64, err error) {
+
+	n := len(ref)
+
+	m := make([][]float64, n)
+	for i, t := range ref {
+		m[i] = make([]float64, n+1)
+
+		// T_0, ..., T_{n-2} at t
+		tPrev, tCur := 1.0, t
+		m[i][0] = 1
+		for k := 1; k < n-1; k++ {
+			m[i][k] = tCur
+			tPrev, tCur = tCur, 2*t*tCur-tPrev
+		}
+
+		m[i][n-1] = math.Pow(-1, float64(i))
+		m[i][n] = f(fromUnit(t, a, b))
+	}
+
+	// Gaussian elimination with partial pivoting
+	for col := 0; col < n; col++ {
+
+		pivot := col
+		for i := col + 1; i < n; i++ {
+			if math.Abs(m[i][col]) > math.Abs(m[pivot][col]) {
+				pivot = i
+			}
+		}
+
+		if m[pivot][col] == 0 {
+			return nil, fmt.Errorf("singular Remez system, the reference points are not distinct")
+		}
+
+		m[col], m[pivot] = m[pivot], m[col]
+
+		for i := col + 1; i < n; i++ {
+			r := m[i][col] / m[col][col]
+			for j := col; j <= n; j++ {
+				m[i][j] -= r * m[col][j]
+			}
+		}
+	}
+
+	x := make([]float64, n)
+	for i := n - 1; i >= 0; i-- {
+		s := m[i][n]
+		for j := i + 1; j < n; j++ {
+			s -= m[i][j] * x[j]
+		}
+		x[i] = s / m[i][i]
+	}
+
+	// x[n-1] is the levelled error E
+	return x[:n-1], nil
+}
@@ -98,8 +98,8 @@ func (p *bootstrappingParametersLiteral) ReadFrom(r io.Reader) (n int64, err err
 		bytes := make([]byte, size)
 
 		var inc int
-		if inc, err = r.Read(bytes); err != nil {
-			return n + int64(inc), fmt.Errorf("io.Reader.Read: %w", err)
+		if inc, err = io.ReadFull(r, bytes); err != nil {
+			return n + int64(inc), fmt.Errorf("io.ReadFull: %w", err)
 		}
 
 		return n + int64(inc), p.UnmarshalJSON(bytes)
@@ -0,0 +1,309 @@
+package utils
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/tuneinsight/lattigo/v5/core/rlwe"
+	"github.com/tuneinsight/lattigo/v5/he/hefloat"
+	"github.com/tuneinsight/lattigo/v5/ring"
+	"github.com/tuneinsight/lattigo/v5/utils/buffer"
+)
+
+// CiphertextVector is a vector of values split across ciphertexts: with n
+// values per ciphertext, the i-th ciphertext holds the values [i*n, (i+1)*n).
+type CiphertextVector []*rlwe.Ciphertext
+
+// Ciphertexts is a collection of named ciphertext vectors, the input and
+// the output of SolveTestcase.
+type Ciphertexts map[string]CiphertextVector
+
+// SplitSlots splits values in chunks of slots values, the last one possibly shorter.
+func SplitSlots(values []complex128, slots int) (chunks [][]complex128) {
+	for i := 0; i < len(values); i += slots {
+		chunks = append(chunks, values[i:min(i+slots, len(values))])
+	}
+	return
+}
+
+// EncryptVector encodes values at the given level, slots values per
+// plaintext, and encrypts them. With ring.ConjugateInvariant, the values
+// are encoded as []float64 and must have no imaginary part.
+func EncryptVector(params hefloat.Parameters, ecd *hefloat.Encoder, enc *rlwe.Encryptor, values []complex128, slots, level int) (v CiphertextVector, err error) {
+
+	if slots < 1 || slots > params.MaxSlots() {
+		return nil, fmt.Errorf("cannot EncryptVector: invalid number of values per ciphertext %d, the parameters have %d slots", slots, params.MaxSlots())
+	}
+
+	for _, chunk := range SplitSlots(values, slots) {
+
+		var values interface{} = chunk
+		if params.RingType() == ring.ConjugateInvariant {
+			if values, err = realParts(chunk); err != nil {
+				return nil, fmt.Errorf("cannot EncryptVector: %w", err)
+			}
+		}
+
+		pt := hefloat.NewPlaintext(params, level)
+		if err = ecd.Encode(values, pt); err != nil {
+			return nil, fmt.Errorf("%T.Encode: %w", ecd, err)
+		}
+
+		var ct *rlwe.Ciphertext
+		if ct, err = enc.EncryptNew(pt); err != nil {
+			return nil, fmt.Errorf("%T.EncryptNew: %w", enc, err)
+		}
+
+		v = append(v, ct)
+	}
+
+	return
+}
+
+// DecryptVector decrypts and decodes the first slots values of every ciphertext
+// of the vector and returns their concatenation. With ring.ConjugateInvariant,
+// the values are decoded as []float64.
+func DecryptVector(params hefloat.Parameters, ecd *hefloat.Encoder, dec *rlwe.Decryptor, v CiphertextVector, slots int) (values []complex128, err error) {
+
+	for i, ct := range v {
+
+		if slots > ct.Slots() {
+			return nil, fmt.Errorf("cannot DecryptVector: ciphertext %d has %d slots but %d values per ciphertext are expected", i, ct.Slots(), slots)
+		}
+
+		pt := dec.DecryptNew(ct)
+
+		if params.RingType() == ring.ConjugateInvariant {
+
+			have := make([]float64, ct.Slots())
+			if err = ecd.Decode(pt, have); err != nil {
+				return nil, fmt.Errorf("%T.Decode: %w", ecd, err)
+			}
+
+			for _, x := range have[:slots] {
+				values = append(values, complex(x, 0))
+			}
+
+			continue
+		}
+
+		have := make([]complex128, ct.Slots())
+		if err = ecd.Decode(pt, have); err != nil {
+			return nil, fmt.Errorf("%T.Decode: %w", ecd, err)
+		}
+
+		values = append(values, have[:slots]...)
+	}
+
+	return
+}
+
+// realParts returns the real parts of values, or an error if one of them has an imaginary part.
+func realParts(values []complex128) (re []float64, err error) {
+	re = make([]float64, len(values))
+	for i, x := range values {
+		if imag(x) != 0 {
+			return nil, fmt.Errorf("value %d has an imaginary part but the slots of ring.ConjugateInvariant are real", i)
+		}
+		re[i] = real(x)
+	}
+	return
+}
+
+// Names returns the sorted names of the vectors.
+func (c Ciphertexts) Names() (names []string) {
+	for name := range c {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return
+}
+
+// Get returns the vector name, or an error listing the available vectors.
+func (c Ciphertexts) Get(name string) (v CiphertextVector, err error) {
+	v, ok := c[name]
+	if !ok {
+		return nil, fmt.Errorf("no ciphertext vector named %q, available vectors are %v", name, c.Names())
+	}
+	return
+}
+
+// BinarySize returns the serialized size of the vector in bytes.
+func (v CiphertextVector) BinarySize() (size int) {
+	size = 4
+	for _, ct := range v {
+		size += ct.BinarySize()
+	}
+	return
+}
+
+// WriteTo writes the number of ciphertexts followed by the ciphertexts.
+func (v CiphertextVector) WriteTo(w io.Writer) (n int64, err error) {
+	switch w := w.(type) {
+	case buffer.Writer:
+
+		var inc int64
+		if inc, err = buffer.WriteAsUint32[int](w, len(v)); err != nil {
+			return n + inc, fmt.Errorf("buffer.WriteAsUint32[int]: %w", err)
+		}
+
+		n += inc
+
+		for i, ct := range v {
+
+			if ct == nil {
+				return n, fmt.Errorf("ciphertext %d is nil", i)
+			}
+
+			if inc, err = ct.WriteTo(w); err != nil {
+				return n + inc, fmt.Errorf("ciphertext %d: %T.WriteTo: %w", i, ct, err)
+			}
+
+			n += inc
+		}
+
+		return n, w.Flush()
+
+	default:
+		return v.WriteTo(bufio.NewWriter(w))
+	}
+}
+
+// ReadFrom reads a vector written by WriteTo.
+func (v *CiphertextVector) ReadFrom(r io.Reader) (n int64, err error) {
+	switch r := r.(type) {
+	case buffer.Reader:
+
+		var size int
+		var inc int64
+		if inc, err = buffer.ReadAsUint32[int](r, &size); err != nil {
+			return n + inc, fmt.Errorf("buffer.ReadAsUint32[int]: %w", err)
+		}
+
+		n += inc
+
+		// The vector grows with the ciphertexts actually read, as size
+		// comes from the file and could be forged
+		*v = make(CiphertextVector, 0, min(size, 64))
+
+		for i := 0; i < size; i++ {
+
+			ct := &rlwe.Ciphertext{}
+			if inc, err = ct.ReadFrom(r); err != nil {
+				return n + inc, fmt.Errorf("ciphertext %d: %T.ReadFrom: %w", i, ct, err)
+			}
+
+			n += inc
+
+			*v = append(*v, ct)
+		}
+
+		return
+
+	default:
+		return v.ReadFrom(bufio.NewReader(r))
+	}
+}
+
+// BinarySize returns the serialized size of the collection in bytes.
+func (c Ciphertexts) BinarySize() (size int) {
+	size = 4
+	for name, v := range c {
+		size += 4 + len(name) + v.BinarySize()
+	}
+	return
+}
+
+// WriteTo writes the number of vectors followed by, sorted by name, the
+// length of the name, the name and the vector.
+func (c Ciphertexts) WriteTo(w io.Writer) (n int64, err error) {
+	switch w := w.(type) {
+	case buffer.Writer:
+
+		var inc int64
+		if inc, err = buffer.WriteAsUint32[int](w, len(c)); err != nil {
+			return n + inc, fmt.Errorf("buffer.WriteAsUint32[int]: %w", err)
+		}
+
+		n += inc
+
+		for _, name := range c.Names() {
+
+			if inc, err = buffer.WriteAsUint32[int](w, len(name)); err != nil {
+				return n + inc, fmt.Errorf("buffer.WriteAsUint32[int]: %w", err)
+			}
+
+			n += inc
+
+			var m int
+			if m, err = w.Write([]byte(name)); err != nil {
+				return n + int64(m), fmt.Errorf("io.Writer.Write: %w", err)
+			}
+
+			n += int64(m)
+
+			if inc, err = c[name].WriteTo(w); err != nil {
+				return n + inc, fmt.Errorf("%s: %w", name, err)
+			}
+
+			n += inc
+		}
+
+		return n, w.Flush()
+
+	default:
+		return c.WriteTo(bufio.NewWriter(w))
+	}
+}
+
+// ReadFrom reads a collection written by WriteTo.
+func (c *Ciphertexts) ReadFrom(r io.Reader) (n int64, err error) {
+	switch r := r.(type) {
+	case buffer.Reader:
+
+		var size int
+		var inc int64
+		if inc, err = buffer.ReadAsUint32[int](r, &size); err != nil {
+			return n + inc, fmt.Errorf("buffer.ReadAsUint32[int]: %w", err)
+		}
+
+		n += inc
+
+		*c = Ciphertexts{}
+
+		for i := 0; i < size; i++ {
+
+			var length int
+			if inc, err = buffer.ReadAsUint32[int](r, &length); err != nil {
+				return n + inc, fmt.Errorf("buffer.ReadAsUint32[int]: %w", err)
+			}
+
+			n += inc
+
+			// The name grows with the bytes actually read, as length could be forged
+			var name bytes.Buffer
+			if inc, err = io.CopyN(&name, r, int64(length)); err != nil {
+				return n + inc, fmt.Errorf("io.CopyN: %w", err)
+			}
+
+			n += inc
+
+			var v CiphertextVector
+			if inc, err = v.ReadFrom(r); err != nil {
+				return n + inc, fmt.Errorf("%s: %w", name.String(), err)
+			}
+
+			n += inc
+
+			(*c)[name.String()] = v
+		}
+
+		return
+
+	default:
+		return c.ReadFrom(bufio.NewReader(r))
+	}
+}
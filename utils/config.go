@@ -0,0 +1,74 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+)
+
+// ReplaceConfigField returns a copy of the JSON object data where the value
+// of the top-level field key is replaced by value. The rest of the document
+// is kept byte for byte. If the field does not exist, it is appended.
+func ReplaceConfigField(data []byte, key string, value interface{}) (out []byte, err error) {
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+
+	var tok json.Token
+	if tok, err = dec.Token(); err != nil {
+		return nil, fmt.Errorf("json.Decoder.Token: %w", err)
+	}
+
+	if delim, ok := tok.(json.Delim); !ok || delim != '{' {
+		return nil, fmt.Errorf("invalid config: top-level value is not a JSON object")
+	}
+
+	start, end := -1, -1
+	for dec.More() {
+
+		if tok, err = dec.Token(); err != nil {
+			return nil, fmt.Errorf("json.Decoder.Token: %w", err)
+		}
+
+		var raw json.RawMessage
+		if err = dec.Decode(&raw); err != nil {
+			return nil, fmt.Errorf("json.Decoder.Decode: %w", err)
+		}
+
+		if tok == key {
+			end = int(dec.InputOffset())
+			start = end - len(raw)
+		}
+	}
+
+	// Offset of the closing brace
+	if _, err = dec.Token(); err != nil {
+		return nil, fmt.Errorf("json.Decoder.Token: %w", err)
+	}
+	closing := int(dec.InputOffset()) - 1
+
+	var field []byte
+	if field, err = json.MarshalIndent(value, "    ", "    "); err != nil {
+		return nil, fmt.Errorf("json.MarshalIndent: %w", err)
+	}
+
+	if start == -1 {
+		// Trims the whitespaces preceding the closing brace
+		prev := closing
+		for prev > 0 && bytes.ContainsRune([]byte(" \t\r\n"), rune(data[prev-1])) {
+			prev--
+		}
+
+		out = append(out, data[:prev]...)
+		if data[prev-1] != '{' {
+			out = append(out, ',')
+		}
+		out = append(out, fmt.Sprintf("\n\n    %q: ", key)...)
+		out = append(out, field...)
+		out = append(out, '\n')
+		return append(out, data[closing:]...), nil
+	}
+
+	out = append(out, data[:start]...)
+	out = append(out, field...)
+	return append(out, data[end:]...), nil
+}
@@ -0,0 +1,219 @@
+package utils
+
+import (
+	"fmt"
+
+	"github.com/tuneinsight/lattigo/v5/core/rlwe"
+	"github.com/tuneinsight/lattigo/v5/he"
+	"github.com/tuneinsight/lattigo/v5/he/hefloat"
+	"github.com/tuneinsight/lattigo/v5/he/hefloat/bootstrapping"
+)
+
+// Evaluator is the homomorphic evaluator given to SolveTestcase.
+// It is implemented by *hefloat.Evaluator and by the wrappers of the template,
+// such as the debug evaluator of main.go --debug-sk. It satisfies he.Evaluator,
+// and can thus be given to hefloat.NewPolynomialEvaluator.
+type Evaluator interface {
+	he.Evaluator
+	GetParameters() *hefloat.Parameters
+	MulRelinNew(op0 *rlwe.Ciphertext, op1 rlwe.Operand) (opOut *rlwe.Ciphertext, err error)
+	MulRelinThenAdd(op0 *rlwe.Ciphertext, op1 rlwe.Operand, opOut *rlwe.Ciphertext) (err error)
+	RelinearizeNew(op0 *rlwe.Ciphertext) (opOut *rlwe.Ciphertext, err error)
+	Rotate(op0 *rlwe.Ciphertext, k int, opOut *rlwe.Ciphertext) (err error)
+	RotateNew(op0 *rlwe.Ciphertext, k int) (opOut *rlwe.Ciphertext, err error)
+	Conjugate(op0 *rlwe.Ciphertext, opOut *rlwe.Ciphertext) (err error)
+	ConjugateNew(op0 *rlwe.Ciphertext) (opOut *rlwe.Ciphertext, err error)
+	DropLevel(op0 *rlwe.Ciphertext, levels int)
+	DropLevelNew(op0 *rlwe.Ciphertext, levels int) (opOut *rlwe.Ciphertext)
+}
+
+var _ Evaluator = (*hefloat.Evaluator)(nil)
+
+// Profiler is implemented by the evaluators recording the time spent in named
+// spans of operations, such as the profiling evaluator of main.go --profile.
+type Profiler interface {
+	Begin(name string) (end func())
+}
+
+// Begin opens the span name if eval is a Profiler, and returns the function
+// closing it, which does nothing otherwise:
+//
+//	defer utils.Begin(eval, "Sigmoid")()
+func Begin(eval Evaluator, name string) (end func()) {
+	if p, ok := eval.(Profiler); ok {
+		return p.Begin(name)
+	}
+	return func() {}
+}
+
+// find returns the first of eval and of the evaluators it wraps, through
+// their method Unwrap, that implements T.
+func find[T any](eval Evaluator) (t T, ok bool) {
+
+	for eval != nil {
+
+		if t, ok = eval.(T); ok {
+			return
+		}
+
+		w, isWrapper := eval.(interface{ Unwrap() Evaluator })
+		if !isWrapper {
+			break
+		}
+
+		eval = w.Unwrap()
+	}
+
+	return
+}
+
+// Sequential is implemented by the evaluators that evaluate one operation at a
+// time, such as the simulator, whose trace lists the operations in the order
+// they are evaluated: a Pool on top of them has a single worker, so that the
+// order does not depend on the scheduling.
+type Sequential interface {
+	Sequential() bool
+}
+
+// ShallowCopier is implemented by the wrappers of the template that can be
+// copied to evaluate concurrently, such as the debug and profiling evaluators.
+type ShallowCopier interface {
+	ShallowCopy() (Evaluator, error)
+}
+
+// ShallowCopy returns a copy of eval that can be used concurrently with eval:
+// the copy shares the keys and the read-only data of eval but has its own buffers.
+// eval must be a *hefloat.Evaluator or a ShallowCopier.
+func ShallowCopy(eval Evaluator) (Evaluator, error) {
+	switch eval := eval.(type) {
+	case ShallowCopier:
+		return eval.ShallowCopy()
+	case *hefloat.Evaluator:
+		return eval.ShallowCopy(), nil
+	default:
+		return nil, fmt.Errorf("cannot ShallowCopy: %T is neither a *hefloat.Evaluator nor a utils.ShallowCopier", eval)
+	}
+}
+
+// BootstrapperFactory is implemented by the evaluators providing their own
+// bootstrapper, such as the simulator of simulate.go, which needs no keys.
+type BootstrapperFactory interface {
+	NewBootstrapper(params bootstrapping.Parameters) (he.Bootstrapper[rlwe.Ciphertext], error)
+}
+
+// NewBootstrapper returns the bootstrapper to use with eval, nil if the
+// bootstrapping is disabled: that of eval, or of an evaluator it wraps, if it
+// is a BootstrapperFactory, and otherwise a *bootstrapping.Evaluator
+// instantiated with the bootstrapping keys of evk, wrapped by eval (see
+// WrapBootstrapper).
+func NewBootstrapper(params Parameters, evk EvaluationKeySet, eval Evaluator) (btp he.Bootstrapper[rlwe.Ciphertext], err error) {
+
+	if params.Bootstrapping == nil {
+		return nil, nil
+	}
+
+	defer Begin(eval, "NewBootstrapper")()
+
+	if f, ok := find[BootstrapperFactory](eval); ok {
+		if btp, err = f.NewBootstrapper(*params.Bootstrapping); err != nil {
+			return nil, fmt.Errorf("cannot NewBootstrapper: %w", err)
+		}
+		return WrapBootstrapper(eval, btp), nil
+	}
+
+	// Read from disk with main.go --lazy-keys
+	keys, err := evk.GetBootstrappingKeys()
+	if err != nil {
+		return nil, fmt.Errorf("cannot NewBootstrapper: %w", err)
+	}
+
+	evaluator, err := bootstrapping.NewEvaluator(*params.Bootstrapping, keys)
+	if err != nil {
+		return nil, fmt.Errorf("cannot NewBootstrapper: %w", err)
+	}
+
+	return WrapBootstrapper(eval, evaluator), nil
+}
+
+// BootstrapperWrapper is implemented by the evaluators wrapping the bootstrappers
+// used with them, such as the profiling evaluator of main.go --profile, which
+// records the bootstrappings and checks the time limit.
+type BootstrapperWrapper interface {
+	WrapBootstrapper(btp he.Bootstrapper[rlwe.Ciphertext]) he.Bootstrapper[rlwe.Ciphertext]
+}
+
+// WrapBootstrapper returns btp wrapped by eval, or by an evaluator it wraps, if
+// it is a BootstrapperWrapper, and btp otherwise, or nil if btp is nil.
+// A bootstrapper already wrapped is unwrapped first, so that it is wrapped once.
+// utils.NewBootstrapper, utils.NewPool and approx.NewEvaluator wrap their bootstrapper.
+func WrapBootstrapper(eval Evaluator, btp he.Bootstrapper[rlwe.Ciphertext]) he.Bootstrapper[rlwe.Ciphertext] {
+
+	if btp = UnwrapBootstrapper(btp); btp == nil {
+		return nil
+	}
+
+	if w, ok := find[BootstrapperWrapper](eval); ok {
+		return w.WrapBootstrapper(btp)
+	}
+
+	return btp
+}
+
+// UnwrapBootstrapper returns the bootstrapper wrapped by btp, through their
+// method Unwrap, or nil if btp is nil, including a nil *bootstrapping.Evaluator.
+func UnwrapBootstrapper(btp he.Bootstrapper[rlwe.Ciphertext]) he.Bootstrapper[rlwe.Ciphertext] {
+
+	for {
+		switch b := btp.(type) {
+		case nil:
+			return nil
+		case *bootstrapping.Evaluator:
+			if b == nil {
+				return nil
+			}
+		}
+
+		w, ok := btp.(interface {
+			Unwrap() he.Bootstrapper[rlwe.Ciphertext]
+		})
+
+		if !ok {
+			return btp
+		}
+
+		btp = w.Unwrap()
+	}
+}
+
+// BootstrapperShallowCopier is implemented by the bootstrappers of the template
+// that can be copied to evaluate concurrently, such as the simulated bootstrapper.
+type BootstrapperShallowCopier interface {
+	ShallowCopy() (he.Bootstrapper[rlwe.Ciphertext], error)
+}
+
+// ShallowCopyBootstrapper returns a copy of btp that can be used concurrently
+// with btp, or nil if btp is nil. btp must be a *bootstrapping.Evaluator or a
+// BootstrapperShallowCopier.
+func ShallowCopyBootstrapper(btp he.Bootstrapper[rlwe.Ciphertext]) (he.Bootstrapper[rlwe.Ciphertext], error) {
+	switch btp := btp.(type) {
+	case nil:
+		return nil, nil
+	case BootstrapperShallowCopier:
+		return btp.ShallowCopy()
+	case *bootstrapping.Evaluator:
+		if btp == nil {
+			return nil, nil
+		}
+		// bootstrapping.Evaluator.ShallowCopy drops the parameters and the
+		// keys, so the read-only fields are copied by value and only the
+		// evaluators holding buffers are reallocated.
+		cp := *btp
+		params := btp.BootstrappingParameters
+		cp.Evaluator = btp.Evaluator.ShallowCopy()
+		cp.DFTEvaluator = hefloat.NewDFTEvaluator(params, cp.Evaluator)
+		cp.Mod1Evaluator = hefloat.NewMod1Evaluator(cp.Evaluator, hefloat.NewPolynomialEvaluator(params, cp.Evaluator), btp.Mod1Parameters)
+		return &cp, nil
+	default:
+		return nil, fmt.Errorf("cannot ShallowCopyBootstrapper: %T is neither a *bootstrapping.Evaluator nor a utils.BootstrapperShallowCopier", btp)
+	}
+}
@@ -0,0 +1,141 @@
+package utils
+
+import (
+	"log"
+	"sync"
+
+	"github.com/tuneinsight/lattigo/v5/core/rlwe"
+	"github.com/tuneinsight/lattigo/v5/he/hefloat"
+	"github.com/tuneinsight/lattigo/v5/utils"
+)
+
+// KeyRecorder is an rlwe.EvaluationKeySet that generates the evaluation keys
+// on demand with the secret key and records every request made to it.
+// It is meant to dry-run a solution to discover which keys it needs.
+type KeyRecorder struct {
+	mu     sync.Mutex
+	params hefloat.Parameters
+	kgen   *rlwe.KeyGenerator
+	sk     *rlwe.SecretKey
+	rlk    *rlwe.RelinearizationKey
+	gks    map[uint64]*rlwe.GaloisKey
+
+	// GaloisRequests counts the requests per Galois element.
+	GaloisRequests map[uint64]int
+
+	// RelinearizationRequests counts the requests for the relinearization key.
+	RelinearizationRequests int
+
+	// Logger, if not nil, logs the first request of every key.
+	Logger *log.Logger
+}
+
+// NewKeyRecorder returns a new KeyRecorder generating the keys with sk.
+func NewKeyRecorder(params hefloat.Parameters, sk *rlwe.SecretKey) *KeyRecorder {
+	return &KeyRecorder{
+		params:         params,
+		kgen:           rlwe.NewKeyGenerator(params),
+		sk:             sk,
+		gks:            map[uint64]*rlwe.GaloisKey{},
+		GaloisRequests: map[uint64]int{},
+	}
+}
+
+// GetGaloisKey records the request and returns the Galois key for galEl,
+// generating it on first use.
+func (kr *KeyRecorder) GetGaloisKey(galEl uint64) (gk *rlwe.GaloisKey, err error) {
+	kr.mu.Lock()
+	defer kr.mu.Unlock()
+
+	kr.GaloisRequests[galEl]++
+
+	var ok bool
+	if gk, ok = kr.gks[galEl]; !ok {
+		if kr.Logger != nil {
+			if k, ok := kr.rotation(galEl); ok {
+				kr.Logger.Printf("GaloisKey[%d]: rotation %d", galEl, k)
+			} else {
+				kr.Logger.Printf("GaloisKey[%d]", galEl)
+			}
+		}
+		gk = kr.kgen.GenGaloisKeyNew(galEl, kr.sk)
+		kr.gks[galEl] = gk
+	}
+
+	return
+}
+
+// GetGaloisKeysList returns the Galois elements requested so far.
+// The list always contains the identity element: rlwe.NewEvaluator only
+// allocates its automorphism index when this list is not empty, and the
+// Galois keys generated afterwards could otherwise not be used.
+func (kr *KeyRecorder) GetGaloisKeysList() (galEls []uint64) {
+	kr.mu.Lock()
+	defer kr.mu.Unlock()
+
+	galEls = []uint64{1}
+	for _, galEl := range utils.GetSortedKeys(kr.gks) {
+		if galEl != 1 {
+			galEls = append(galEls, galEl)
+		}
+	}
+
+	return
+}
+
+// GetRelinearizationKey records the request and returns the relinearization
+// key, generating it on first use.
+func (kr *KeyRecorder) GetRelinearizationKey() (rlk *rlwe.RelinearizationKey, err error) {
+	kr.mu.Lock()
+	defer kr.mu.Unlock()
+
+	kr.RelinearizationRequests++
+
+	if kr.rlk == nil {
+		if kr.Logger != nil {
+			kr.Logger.Printf("RelinearizationKey")
+		}
+		kr.rlk = kr.kgen.GenRelinearizationKeyNew(kr.sk)
+	}
+
+	return kr.rlk, nil
+}
+
+// GaloisElements returns the sorted list of Galois elements requested so far.
+func (kr *KeyRecorder) GaloisElements() (galEls []uint64) {
+	kr.mu.Lock()
+	defer kr.mu.Unlock()
+	return utils.GetSortedKeys(kr.gks)
+}
+
+// Literal returns the minimal "EvaluationKeys" block covering the requests
+// recorded so far. Galois elements are expressed as rotations whenever possible.
+func (kr *KeyRecorder) Literal() (lit EvaluationKeysLiteral) {
+	kr.mu.Lock()
+	defer kr.mu.Unlock()
+
+	lit.Rotations = []int{}
+	lit.GaloisElements = []uint64{}
+	lit.Relinearization = kr.rlk != nil
+
+	for _, galEl := range utils.GetSortedKeys(kr.gks) {
+		if k, ok := kr.rotation(galEl); ok {
+			lit.Rotations = append(lit.Rotations, k)
+		} else {
+			lit.GaloisElements = append(lit.GaloisElements, galEl)
+		}
+	}
+
+	return
+}
+
+// rotation returns the slot rotation k, in the range (-slots/2, slots/2],
+// such that GaloisElement(k) = galEl, if any.
+func (kr *KeyRecorder) rotation(galEl uint64) (k int, ok bool) {
+	slots := kr.params.MaxSlots()
+	k = kr.params.SolveDiscreteLogGaloisElement(galEl) % slots
+	if k > slots>>1 {
+		k -= slots
+	}
+	return k, kr.params.GaloisElement(k) == galEl
+}
@@ -0,0 +1,659 @@
+package utils
+
+import (
+	"bufio"
+	"container/list"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"sync"
+
+	"github.com/tuneinsight/lattigo/v5/core/rlwe"
+	"github.com/tuneinsight/lattigo/v5/he/hefloat/bootstrapping"
+	"github.com/tuneinsight/lattigo/v5/utils/buffer"
+)
+
+// The payload of a serialized EvaluationKeySet is indexed:
+//
+//	index length uint32
+//	index        []byte JSON of keyIndex
+//	keys         []byte WriteTo of every key, at the offsets given by the index
+//
+// Offsets are relative to the first byte after the index, so that a key can
+// be read on its own by KeyFile. The keys of a seeded set are written without
+// their uniform part, which is regenerated from the seed of the index when
+// they are read (see seeded.go).
+
+// keyEntry locates a serialized key.
+type keyEntry struct {
+	Offset int64
+	Size   int64
+}
+
+type galoisKeyEntry struct {
+	GaloisElement uint64
+	keyEntry
+}
+
+type bootstrappingKeyIndex struct {
+	EvkN1ToN2        *keyEntry `json:",omitempty"`
+	EvkN2ToN1        *keyEntry `json:",omitempty"`
+	EvkRealToCmplx   *keyEntry `json:",omitempty"`
+	EvkCmplxToReal   *keyEntry `json:",omitempty"`
+	EvkDenseToSparse *keyEntry `json:",omitempty"`
+	EvkSparseToDense *keyEntry `json:",omitempty"`
+	Relinearization  *keyEntry `json:",omitempty"`
+	GaloisKeys       []galoisKeyEntry
+}
+
+type keyIndex struct {
+	Scheme          bool      // The set has scheme keys (possibly none)
+	Relinearization *keyEntry `json:",omitempty"`
+	GaloisKeys      []galoisKeyEntry
+	Bootstrapping   *bootstrappingKeyIndex `json:",omitempty"`
+	GaloisKeysInfo  []GaloisKeyInfo
+	Seed            []byte `json:",omitempty"`
+}
+
+type serializableKey interface {
+	io.WriterTo
+	BinarySize() int
+}
+
+// indexedKey is a key of the set along with its entry in the index.
+type indexedKey struct {
+	name  string
+	entry *keyEntry
+	key   serializableKey
+}
+
+// index returns the index of the set and the keys in the order of their offsets.
+func (evk EvaluationKeySet) index() (idx keyIndex, keys []indexedKey, err error) {
+
+	var offset int64
+
+	add := func(name string, key serializableKey) *keyEntry {
+		if evk.Seed != nil {
+			key = compressKey(key)
+		}
+		entry := &keyEntry{Offset: offset, Size: int64(key.BinarySize())}
+		offset += entry.Size
+		keys = append(keys, indexedKey{name: name, entry: entry, key: key})
+		return entry
+	}
+
+	addGaloisKeys := func(prefix string, set rlwe.EvaluationKeySet) (entries []galoisKeyEntry, err error) {
+		galEls := set.GetGaloisKeysList()
+		sort.Slice(galEls, func(i, j int) bool { return galEls[i] < galEls[j] })
+		for _, galEl := range galEls {
+			var gk *rlwe.GaloisKey
+			if gk, err = set.GetGaloisKey(galEl); err != nil {
+				return nil, fmt.Errorf("GetGaloisKey(%d): %w", galEl, err)
+			}
+			entries = append(entries, galoisKeyEntry{GaloisElement: galEl, keyEntry: *add(galoisKeyName(prefix, galEl), gk)})
+		}
+		return
+	}
+
+	idx.GaloisKeysInfo = evk.GaloisKeysInfo
+	idx.Seed = evk.Seed
+
+	if evk.Scheme != nil {
+
+		idx.Scheme = true
+
+		if rlk, err := evk.Scheme.GetRelinearizationKey(); err == nil {
+			idx.Relinearization = add(relinearizationKeyName, rlk)
+		}
+
+		if idx.GaloisKeys, err = addGaloisKeys("", evk.Scheme); err != nil {
+			return
+		}
+	}
+
+	if btp := evk.Bootstrapping; btp != nil {
+
+		idx.Bootstrapping = &bootstrappingKeyIndex{}
+
+		for _, key := range []struct {
+			name  string
+			evk   *rlwe.EvaluationKey
+			entry **keyEntry
+		}{
+			{"EvkN1ToN2", btp.EvkN1ToN2, &idx.Bootstrapping.EvkN1ToN2},
+			{"EvkN2ToN1", btp.EvkN2ToN1, &idx.Bootstrapping.EvkN2ToN1},
+			{"EvkRealToCmplx", btp.EvkRealToCmplx, &idx.Bootstrapping.EvkRealToCmplx},
+			{"EvkCmplxToReal", btp.EvkCmplxToReal, &idx.Bootstrapping.EvkCmplxToReal},
+			{"EvkDenseToSparse", btp.EvkDenseToSparse, &idx.Bootstrapping.EvkDenseToSparse},
+			{"EvkSparseToDense", btp.EvkSparseToDense, &idx.Bootstrapping.EvkSparseToDense},
+		} {
+			if key.evk != nil {
+				*key.entry = add(key.name, key.evk)
+			}
+		}
+
+		if btp.MemEvaluationKeySet != nil {
+
+			if btp.RelinearizationKey != nil {
+				idx.Bootstrapping.Relinearization = add(bootstrappingPrefix+relinearizationKeyName, btp.RelinearizationKey)
+			}
+
+			if idx.Bootstrapping.GaloisKeys, err = addGaloisKeys(bootstrappingPrefix, btp.MemEvaluationKeySet); err != nil {
+				return
+			}
+		}
+	}
+
+	return
+}
+
+// writeIndexed writes the index of the set followed by its keys.
+func (evk EvaluationKeySet) writeIndexed(w buffer.Writer) (n int64, err error) {
+
+	idx, keys, err := evk.index()
+	if err != nil {
+		return
+	}
+
+	var data []byte
+	if data, err = json.Marshal(idx); err != nil {
+		return
+	}
+
+	var inc int64
+	if inc, err = buffer.WriteAsUint32[int](w, len(data)); err != nil {
+		return n, fmt.Errorf("buffer.WriteAsUint32[int]: %w", err)
+	}
+
+	n += inc
+
+	var m int
+	if m, err = w.Write(data); err != nil {
+		return n, fmt.Errorf("io.Write.Write: %w", err)
+	}
+
+	n += int64(m)
+
+	for _, key := range keys {
+
+		if inc, err = key.key.WriteTo(w); err != nil {
+			return n, fmt.Errorf("%s.WriteTo: %w", key.name, err)
+		}
+
+		if inc != key.entry.Size {
+			return n, fmt.Errorf("%s.WriteTo: wrote %d bytes but BinarySize is %d", key.name, inc, key.entry.Size)
+		}
+
+		n += inc
+	}
+
+	return n, w.Flush()
+}
+
+// readIndex reads the index of a serialized set, and returns its size in bytes.
+func readIndex(r io.Reader) (idx keyIndex, n int64, err error) {
+
+	var size uint32
+	if err = binary.Read(r, binary.LittleEndian, &size); err != nil {
+		return idx, n, fmt.Errorf("binary.Read: %w", err)
+	}
+
+	n += 4
+
+	data := make([]byte, size)
+
+	var m int
+	if m, err = io.ReadFull(r, data); err != nil {
+		return idx, n + int64(m), fmt.Errorf("io.ReadFull: %w", err)
+	}
+
+	n += int64(m)
+
+	if err = json.Unmarshal(data, &idx); err != nil {
+		return idx, n, fmt.Errorf("invalid evaluation key index: %w", err)
+	}
+
+	return
+}
+
+// readKeyFunc reads the key at entry into key.
+type readKeyFunc func(name string, entry keyEntry, key io.ReaderFrom) error
+
+// readKeySet reads a relinearization key and Galois keys with read.
+func readKeySet(prefix string, rlk *keyEntry, gks []galoisKeyEntry, read readKeyFunc) (set *rlwe.MemEvaluationKeySet, err error) {
+
+	set = rlwe.NewMemEvaluationKeySet(nil)
+
+	if rlk != nil {
+		set.RelinearizationKey = &rlwe.RelinearizationKey{}
+		if err = read(prefix+relinearizationKeyName, *rlk, set.RelinearizationKey); err != nil {
+			return
+		}
+	}
+
+	for _, entry := range gks {
+		gk := &rlwe.GaloisKey{}
+		if err = read(galoisKeyName(prefix, entry.GaloisElement), entry.keyEntry, gk); err != nil {
+			return
+		}
+		set.GaloisKeys[entry.GaloisElement] = gk
+	}
+
+	return
+}
+
+// readBootstrappingKeys reads the bootstrapping keys with read.
+func readBootstrappingKeys(idx *bootstrappingKeyIndex, read readKeyFunc) (btp *bootstrapping.EvaluationKeys, err error) {
+
+	btp = &bootstrapping.EvaluationKeys{}
+
+	for _, key := range []struct {
+		name  string
+		evk   **rlwe.EvaluationKey
+		entry *keyEntry
+	}{
+		{"EvkN1ToN2", &btp.EvkN1ToN2, idx.EvkN1ToN2},
+		{"EvkN2ToN1", &btp.EvkN2ToN1, idx.EvkN2ToN1},
+		{"EvkRealToCmplx", &btp.EvkRealToCmplx, idx.EvkRealToCmplx},
+		{"EvkCmplxToReal", &btp.EvkCmplxToReal, idx.EvkCmplxToReal},
+		{"EvkDenseToSparse", &btp.EvkDenseToSparse, idx.EvkDenseToSparse},
+		{"EvkSparseToDense", &btp.EvkSparseToDense, idx.EvkSparseToDense},
+	} {
+		if key.entry != nil {
+			*key.evk = &rlwe.EvaluationKey{}
+			if err = read(key.name, *key.entry, *key.evk); err != nil {
+				return
+			}
+		}
+	}
+
+	btp.MemEvaluationKeySet, err = readKeySet(bootstrappingPrefix, idx.Relinearization, idx.GaloisKeys, read)
+
+	return
+}
+
+// readIndexed reads the keys of a set written by writeIndexed in memory.
+// The keys of a seeded set are left compressed (see Expand).
+func (evk *EvaluationKeySet) readIndexed(r buffer.Reader) (n int64, err error) {
+
+	idx, n, err := readIndex(r)
+	if err != nil {
+		return
+	}
+
+	// The keys are read in the order they were written
+	var offset int64
+	read := func(name string, entry keyEntry, key io.ReaderFrom) (err error) {
+
+		if entry.Offset != offset {
+			return fmt.Errorf("%s: offset %d in the index but the key starts at %d", name, entry.Offset, offset)
+		}
+
+		var inc int64
+		if inc, err = key.ReadFrom(r); err != nil {
+			return fmt.Errorf("%s.ReadFrom: %w", name, err)
+		}
+
+		if inc != entry.Size {
+			return fmt.Errorf("%s: size %d in the index but read %d bytes", name, entry.Size, inc)
+		}
+
+		offset += inc
+		return
+	}
+
+	if idx.Scheme {
+		var set *rlwe.MemEvaluationKeySet
+		if set, err = readKeySet("", idx.Relinearization, idx.GaloisKeys, read); err != nil {
+			return
+		}
+		evk.Scheme = set
+	}
+
+	if idx.Bootstrapping != nil {
+		if evk.Bootstrapping, err = readBootstrappingKeys(idx.Bootstrapping, read); err != nil {
+			return
+		}
+	}
+
+	evk.GaloisKeysInfo = idx.GaloisKeysInfo
+	evk.Seed = idx.Seed
+
+	return n + offset, nil
+}
+
+// KeyFile is an evaluation key set file opened with OpenEvaluationKeySet.
+// It implements rlwe.EvaluationKeySet for the scheme keys: a key is read
+// from the file the first time it is requested and kept in memory as long
+// as the keys in memory fit in the budget, the least recently used keys
+// being evicted first. The bootstrapping keys are read at once, the first
+// time they are requested, and are never evicted.
+//
+// The checksum of the file is not verified; each key is instead checked
+// against the parameters when it is read. Seeded keys are expanded when
+// they are read, which requires the parameters.
+type KeyFile struct {
+	f      *os.File
+	base   int64
+	index  keyIndex
+	params Parameters
+
+	galoisKeys map[uint64]keyEntry
+
+	mu     sync.Mutex
+	budget int64
+	used   int64
+	lru    *list.List // of *cachedKey, most recently used first
+	cache  map[uint64]*list.Element
+	btp    *bootstrapping.EvaluationKeys
+
+	// Reads and Evictions count the keys read from the file and evicted from memory.
+	Reads     int
+	Evictions int
+}
+
+type cachedKey struct {
+	galEl uint64 // 0 for the relinearization key
+	size  int64
+	key   interface{}
+}
+
+// OpenEvaluationKeySet opens a file written by Serialize(EvaluationKeySet, ...)
+// for lazy reading. A budget of zero or less keeps every key read in memory.
+// The returned KeyFile must be closed once the keys are no longer needed.
+func OpenEvaluationKeySet(path string, params *Parameters, budget int64) (kf *KeyFile, err error) {
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("os.Open(%s): %w", path, err)
+	}
+
+	defer func() {
+		if err != nil {
+			f.Close()
+		}
+	}()
+
+	var size uint64
+	if size, err = readHeader(f, path, KindEvaluationKeySet, params); err != nil {
+		return
+	}
+
+	idx, n, err := readIndex(io.LimitReader(f, int64(size)))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	kf = &KeyFile{
+		f:          f,
+		base:       headerSize + n,
+		index:      idx,
+		galoisKeys: map[uint64]keyEntry{},
+		budget:     budget,
+		lru:        list.New(),
+		cache:      map[uint64]*list.Element{},
+	}
+
+	if params != nil {
+		kf.params = *params
+	} else if idx.Seed != nil {
+		return nil, fmt.Errorf("%s: the evaluation keys are seeded and need the parameters to be expanded", path)
+	}
+
+	for _, entry := range idx.GaloisKeys {
+		kf.galoisKeys[entry.GaloisElement] = entry.keyEntry
+	}
+
+	for _, entry := range kf.entries() {
+		if end := n + entry.Offset + entry.Size; end > int64(size) {
+			return nil, fmt.Errorf("%s: the index points past the end of the payload (%d > %d bytes)", path, end, size)
+		}
+	}
+
+	return
+}
+
+// entries returns all the entries of the index.
+func (kf *KeyFile) entries() (entries []keyEntry) {
+
+	if kf.index.Relinearization != nil {
+		entries = append(entries, *kf.index.Relinearization)
+	}
+
+	for _, entry := range kf.index.GaloisKeys {
+		entries = append(entries, entry.keyEntry)
+	}
+
+	if btp := kf.index.Bootstrapping; btp != nil {
+		for _, entry := range []*keyEntry{btp.EvkN1ToN2, btp.EvkN2ToN1, btp.EvkRealToCmplx, btp.EvkCmplxToReal, btp.EvkDenseToSparse, btp.EvkSparseToDense, btp.Relinearization} {
+			if entry != nil {
+				entries = append(entries, *entry)
+			}
+		}
+		for _, entry := range btp.GaloisKeys {
+			entries = append(entries, entry.keyEntry)
+		}
+	}
+
+	return
+}
+
+// EvaluationKeySet returns the set of keys of the file, which reads its
+// scheme keys on first use and its bootstrapping keys with GetBootstrappingKeys.
+func (kf *KeyFile) EvaluationKeySet() EvaluationKeySet {
+	return EvaluationKeySet{Scheme: kf, GaloisKeysInfo: kf.index.GaloisKeysInfo, Seed: kf.index.Seed}
+}
+
+// Close closes the file. Keys already returned remain valid.
+func (kf *KeyFile) Close() error {
+	return kf.f.Close()
+}
+
+// InMemory returns the total size in bytes of the scheme keys held in memory.
+func (kf *KeyFile) InMemory() int64 {
+	kf.mu.Lock()
+	defer kf.mu.Unlock()
+	return kf.used
+}
+
+// read reads the key at entry into key, it is a readKeyFunc.
+// It must be called with kf.mu held.
+func (kf *KeyFile) read(name string, entry keyEntry, key io.ReaderFrom) (err error) {
+
+	r := bufio.NewReader(io.NewSectionReader(kf.f, kf.base+entry.Offset, entry.Size))
+
+	var n int64
+	if n, err = key.ReadFrom(r); err != nil {
+		return fmt.Errorf("%s: %s.ReadFrom: %w", kf.f.Name(), name, err)
+	}
+
+	if n != entry.Size {
+		return fmt.Errorf("%s: %s: size %d in the index but read %d bytes", kf.f.Name(), name, entry.Size, n)
+	}
+
+	kf.Reads++
+
+	return
+}
+
+// get returns the cached key galEl, or reads it with load.
+func (kf *KeyFile) get(galEl uint64, entry keyEntry, load func() (interface{}, error)) (key interface{}, err error) {
+
+	kf.mu.Lock()
+	defer kf.mu.Unlock()
+
+	if e, ok := kf.cache[galEl]; ok {
+		kf.lru.MoveToFront(e)
+		return e.Value.(*cachedKey).key, nil
+	}
+
+	if key, err = load(); err != nil {
+		return
+	}
+
+	for kf.budget > 0 && kf.lru.Len() > 0 && kf.used+entry.Size > kf.budget {
+		e := kf.lru.Back()
+		c := e.Value.(*cachedKey)
+		kf.lru.Remove(e)
+		delete(kf.cache, c.galEl)
+		kf.used -= c.size
+		kf.Evictions++
+	}
+
+	kf.cache[galEl] = kf.lru.PushFront(&cachedKey{galEl: galEl, size: entry.Size, key: key})
+	kf.used += entry.Size
+
+	return
+}
+
+// GetGaloisKey returns the Galois key for galEl, reading it from the file if it is not in memory.
+func (kf *KeyFile) GetGaloisKey(galEl uint64) (gk *rlwe.GaloisKey, err error) {
+
+	entry, ok := kf.galoisKeys[galEl]
+	if !ok {
+		return nil, fmt.Errorf("GaloisKey[%d] is nil", galEl)
+	}
+
+	key, err := kf.get(galEl, entry, func() (interface{}, error) {
+
+		name := galoisKeyName("", galEl)
+
+		gk := &rlwe.GaloisKey{}
+		if err := kf.read(name, entry, gk); err != nil {
+			return nil, err
+		}
+
+		if err := kf.expand(name, &gk.EvaluationKey); err != nil {
+			return nil, err
+		}
+
+		if gk.GaloisElement != galEl {
+			return nil, fmt.Errorf("Galois key indexed by %d is for the Galois element %d", galEl, gk.GaloisElement)
+		}
+
+		if err := kf.checkKey(&gk.EvaluationKey); err != nil {
+			return nil, fmt.Errorf("Galois key %d: %w", galEl, err)
+		}
+
+		return gk, nil
+	})
+
+	if err != nil {
+		return
+	}
+
+	return key.(*rlwe.GaloisKey), nil
+}
+
+// GetGaloisKeysList returns the Galois elements of the scheme keys in the file.
+func (kf *KeyFile) GetGaloisKeysList() (galEls []uint64) {
+	galEls = make([]uint64, len(kf.index.GaloisKeys))
+	for i, entry := range kf.index.GaloisKeys {
+		galEls[i] = entry.GaloisElement
+	}
+	return
+}
+
+// GetRelinearizationKey returns the relinearization key, reading it from the file if it is not in memory.
+func (kf *KeyFile) GetRelinearizationKey() (rlk *rlwe.RelinearizationKey, err error) {
+
+	if kf.index.Relinearization == nil {
+		return nil, fmt.Errorf("RelinearizationKey is nil")
+	}
+
+	key, err := kf.get(0, *kf.index.Relinearization, func() (interface{}, error) {
+
+		rlk := &rlwe.RelinearizationKey{}
+		if err := kf.read(relinearizationKeyName, *kf.index.Relinearization, rlk); err != nil {
+			return nil, err
+		}
+
+		if err := kf.expand(relinearizationKeyName, &rlk.EvaluationKey); err != nil {
+			return nil, err
+		}
+
+		if err := kf.checkKey(&rlk.EvaluationKey); err != nil {
+			return nil, fmt.Errorf("relinearization key: %w", err)
+		}
+
+		return rlk, nil
+	})
+
+	if err != nil {
+		return
+	}
+
+	return key.(*rlwe.RelinearizationKey), nil
+}
+
+// expand regenerates the uniform part of a scheme key if the keys are seeded.
+func (kf *KeyFile) expand(name string, evk *rlwe.EvaluationKey) error {
+	if kf.index.Seed == nil {
+		return nil
+	}
+	return expandKey(*kf.params.Scheme.RingQP(), kf.index.Seed, name, evk)
+}
+
+// checkKey checks a scheme key against the parameters the file was opened with, if any.
+func (kf *KeyFile) checkKey(evk *rlwe.EvaluationKey) error {
+	if kf.params.Scheme.N() == 0 {
+		return nil
+	}
+	return checkEvaluationKey(kf.params.Scheme.Parameters.Parameters, evk)
+}
+
+// Bootstrapping returns the bootstrapping keys, read from the file on the first call.
+func (kf *KeyFile) Bootstrapping() (btp *bootstrapping.EvaluationKeys, err error) {
+
+	kf.mu.Lock()
+	defer kf.mu.Unlock()
+
+	if kf.btp != nil {
+		return kf.btp, nil
+	}
+
+	if kf.index.Bootstrapping == nil {
+		return nil, fmt.Errorf("the evaluation key set has no bootstrapping keys")
+	}
+
+	if btp, err = readBootstrappingKeys(kf.index.Bootstrapping, kf.read); err != nil {
+		return nil, err
+	}
+
+	if kf.index.Seed != nil {
+		if err = expandBootstrappingKeys(kf.params, kf.index.Seed, btp); err != nil {
+			return nil, err
+		}
+	}
+
+	if kf.params.Bootstrapping != nil {
+		if err = checkBootstrappingKeys(kf.params, btp); err != nil {
+			return nil, err
+		}
+	}
+
+	kf.btp = btp
+
+	return
+}
+
+// check checks the index of the file against the parameters without reading the keys.
+func (kf *KeyFile) check(params Parameters) (err error) {
+
+	NthRoot := params.Scheme.RingQ().NthRoot()
+
+	for _, entry := range kf.index.GaloisKeys {
+		if galEl := entry.GaloisElement; galEl&1 == 0 || galEl >= NthRoot {
+			return fmt.Errorf("scheme evaluation keys: Galois element %d is not an odd integer smaller than the ring NthRoot=%d", galEl, NthRoot)
+		}
+	}
+
+	if params.Bootstrapping != nil && kf.index.Bootstrapping == nil {
+		return fmt.Errorf("bootstrapping is enabled but the evaluation key set has no bootstrapping keys")
+	}
+
+	return
+}
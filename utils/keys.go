@@ -2,62 +2,143 @@ package utils
 
 import(
 	"io"
+	"fmt"
 	"bufio"
 	"encoding/json"
 	"github.com/tuneinsight/lattigo/v5/core/rlwe"
+	"github.com/tuneinsight/lattigo/v5/he/hefloat"
 	"github.com/tuneinsight/lattigo/v5/he/hefloat/bootstrapping"
 	"github.com/tuneinsight/lattigo/v5/utils"
 	"github.com/tuneinsight/lattigo/v5/utils/buffer"
 )
 
 type EvaluationKeySet struct{
-	Scheme *rlwe.MemEvaluationKeySet
+	Scheme rlwe.EvaluationKeySet // *rlwe.MemEvaluationKeySet when read from disk, *KeyFile when opened with OpenEvaluationKeySet
 	Bootstrapping *bootstrapping.EvaluationKeys
+	GaloisKeysInfo []GaloisKeyInfo
+	Seed []byte // Not nil if the keys are seeded (see seeded.go), in which case they are written without their uniform part
+}
+
+// EvaluationKeysLiteral is the "EvaluationKeys" block of config.json.
+type EvaluationKeysLiteral struct {
+	Rotations       []int
+	GaloisElements  []uint64
+	Relinearization bool
+	Seeded          bool        `json:",omitempty"` // Generates seeded keys, written in half the size (see seeded.go)
+	Reductions      []Reduction `json:",omitempty"` // Generates the rotations of these reductions (see reduction.go)
+}
+
+// GaloisKeyInfo records which entries of the "EvaluationKeys" block
+// requested the Galois key for GaloisElement.
+type GaloisKeyInfo struct {
+	GaloisElement uint64
+	Rotations     []int `json:",omitempty"` // Entries of [EvaluationKeysLiteral.Rotations] mapping to GaloisElement
+	Explicit      bool  `json:",omitempty"` // GaloisElement is listed in [EvaluationKeysLiteral.GaloisElements]
+	Reductions    []int `json:",omitempty"` // Indices of the entries of [EvaluationKeysLiteral.Reductions] rotating by GaloisElement
+}
+
+// GaloisKeysInfo returns, sorted by Galois element, the de-duplicated list
+// of Galois keys requested by the literal along with their origin.
+func (lit EvaluationKeysLiteral) GaloisKeysInfo(params hefloat.Parameters) (info []GaloisKeyInfo, err error) {
+
+	keys := map[uint64]*GaloisKeyInfo{}
+
+	get := func(galEl uint64) *GaloisKeyInfo {
+		if _, ok := keys[galEl]; !ok {
+			keys[galEl] = &GaloisKeyInfo{GaloisElement: galEl}
+		}
+		return keys[galEl]
+	}
+
+	for _, k := range lit.Rotations {
+		gk := get(params.GaloisElement(k))
+		gk.Rotations = append(gk.Rotations, k)
+	}
+
+	for _, galEl := range lit.GaloisElements {
+		get(galEl).Explicit = true
+	}
+
+	for i, r := range lit.Reductions {
+
+		var rotations []int
+		if rotations, err = r.Rotations(params); err != nil {
+			return nil, fmt.Errorf("Reductions[%d]: %w", i, err)
+		}
+
+		for _, k := range rotations {
+			gk := get(params.GaloisElement(k))
+			if n := len(gk.Reductions); n == 0 || gk.Reductions[n-1] != i {
+				gk.Reductions = append(gk.Reductions, i)
+			}
+		}
+	}
+
+	galEls := utils.GetSortedKeys(keys)
+	info = make([]GaloisKeyInfo, len(galEls))
+	for i, galEl := range galEls {
+		info[i] = *keys[galEl]
+	}
+
+	return
 }
 
 func NewEvaluationKeySet(params Parameters, sk *rlwe.SecretKey, JSON []byte) (evk EvaluationKeySet, err error){
 	aux := struct{
-		EvaluationKeys struct{
-			Rotations []int
-			GaloisElements []uint64
-			Relinearization bool
-		}
+		EvaluationKeys EvaluationKeysLiteral
 	}{}
 
 	if err = json.Unmarshal(JSON, &aux); err != nil{
 		return
 	}
 
+	if aux.EvaluationKeys.Seeded {
+		if evk.Seed, err = NewSeed(); err != nil {
+			return
+		}
+	}
+
+	// With a seed, each key has its own generator (see seededKeyGenerator)
 	kgen := rlwe.NewKeyGenerator(params.Scheme)
+	keyGenerator := func(name string) (*rlwe.KeyGenerator, error) {
+		if evk.Seed == nil {
+			return kgen, nil
+		}
+		return seededKeyGenerator(params.Scheme, evk.Seed, name)
+	}
 
 	var rlk *rlwe.RelinearizationKey
 	if aux.EvaluationKeys.Relinearization {
-		rlk = kgen.GenRelinearizationKeyNew(sk)
-	}
-
-	var gks []*rlwe.GaloisKey
-
-
-	keys := map[uint64]bool{}
-	for _, k := range aux.EvaluationKeys.Rotations{
-		keys[params.Scheme.GaloisElement(k)] = true
+		var g *rlwe.KeyGenerator
+		if g, err = keyGenerator(relinearizationKeyName); err != nil {
+			return
+		}
+		rlk = g.GenRelinearizationKeyNew(sk)
 	}
 
-	for _, galEl := range aux.EvaluationKeys.GaloisElements{
-		keys[galEl] = true
+	if evk.GaloisKeysInfo, err = aux.EvaluationKeys.GaloisKeysInfo(params.Scheme); err != nil{
+		return
 	}
 
-	if  galEls := utils.GetSortedKeys(keys); len(galEls) != 0{
-		gks = make([]*rlwe.GaloisKey, len(aux.EvaluationKeys.GaloisElements))
-		for i, galEl := range aux.EvaluationKeys.GaloisElements{
-			gks[i] = kgen.GenGaloisKeyNew(galEl, sk)
+	gks := make([]*rlwe.GaloisKey, len(evk.GaloisKeysInfo))
+	for i, info := range evk.GaloisKeysInfo {
+		var g *rlwe.KeyGenerator
+		if g, err = keyGenerator(galoisKeyName("", info.GaloisElement)); err != nil {
+			return
 		}
+		gks[i] = g.GenGaloisKeyNew(info.GaloisElement, sk)
 	}
 
 	evk.Scheme = rlwe.NewMemEvaluationKeySet(rlk, gks...)
 
 	if params.Bootstrapping != nil{
-		if evk.Bootstrapping, _, err = params.Bootstrapping.GenEvaluationKeys(sk); err != nil{
+		if evk.Seed != nil {
+			evk.Bootstrapping, err = genSeededBootstrappingKeys(params, evk.Seed, sk)
+		} else {
+			evk.Bootstrapping, _, err = params.Bootstrapping.GenEvaluationKeys(sk)
+		}
+
+		if err != nil{
 			return
 		}
 	}
@@ -65,215 +146,38 @@ func NewEvaluationKeySet(params Parameters, sk *rlwe.SecretKey, JSON []byte) (ev
 	return
 }
 
+// GetBootstrappingKeys returns the bootstrapping keys, which are read from
+// the key file on the first call if the set comes from KeyFile.EvaluationKeySet.
+func (evk EvaluationKeySet) GetBootstrappingKeys() (*bootstrapping.EvaluationKeys, error) {
+
+	if evk.Bootstrapping != nil {
+		return evk.Bootstrapping, nil
+	}
+
+	if kf, ok := evk.Scheme.(*KeyFile); ok {
+		return kf.Bootstrapping()
+	}
+
+	return nil, fmt.Errorf("the evaluation key set has no bootstrapping keys")
+}
+
 func (evk EvaluationKeySet) BinarySize() int {
-	return 1 + evk.Scheme.BinarySize() + 8 + evk.Bootstrapping.BinarySize()
+	idx, keys, err := evk.index()
+	if err != nil {
+		return 0
+	}
+	data, _ := json.Marshal(idx)
+	size := 4 + len(data)
+	for _, key := range keys {
+		size += int(key.entry.Size)
+	}
+	return size
 }
 
 func (evk EvaluationKeySet) WriteTo(w io.Writer) (n int64, err error) {
 	switch w := w.(type) {
 	case buffer.Writer:
-
-		var inc int64
-
-		if evk.Scheme != nil{
-			if inc, err = buffer.WriteAsUint8[int](w, 1); err != nil {
-				return
-			}
-
-
-			n += inc
-
-			if inc, err = evk.Scheme.WriteTo(w); err != nil{
-				return
-			}
-
-			n += inc
-
-		}else{
-			if inc, err = buffer.WriteAsUint8[int](w, 0); err != nil {
-				return
-			}
-
-			n += inc
-		}
-
-		if evk.Bootstrapping != nil{
-
-			if inc, err = buffer.WriteAsUint8[int](w, 1); err != nil {
-				return
-			}
-
-			n += inc
-
-			if evk.Bootstrapping.EvkN1ToN2 != nil {
-
-				if inc, err = buffer.WriteAsUint8[int](w, 1); err != nil {
-					return
-				}
-
-				n += inc
-
-				if inc, err = evk.Bootstrapping.EvkN1ToN2.WriteTo(w); err != nil {
-					return
-				}
-
-				n += inc
-
-			} else {
-
-				if inc, err = buffer.WriteAsUint8[int](w, 0); err != nil {
-					return
-				}
-
-				n += inc
-			}
-
-			if evk.Bootstrapping.EvkN2ToN1 != nil {
-
-				if inc, err = buffer.WriteAsUint8[int](w, 1); err != nil {
-					return
-				}
-
-				n += inc
-
-				if inc, err = evk.Bootstrapping.EvkN2ToN1.WriteTo(w); err != nil {
-					return
-				}
-
-				n += inc
-
-			} else {
-
-				if inc, err = buffer.WriteAsUint8[int](w, 0); err != nil {
-					return
-				}
-
-				n += inc
-			}
-
-			if evk.Bootstrapping.EvkRealToCmplx != nil {
-
-				if inc, err = buffer.WriteAsUint8[int](w, 1); err != nil {
-					return
-				}
-
-				n += inc
-
-				if inc, err = evk.Bootstrapping.EvkRealToCmplx.WriteTo(w); err != nil {
-					return
-				}
-
-				n += inc
-
-			} else {
-
-				if inc, err = buffer.WriteAsUint8[int](w, 0); err != nil {
-					return
-				}
-
-				n += inc
-			}
-
-			if evk.Bootstrapping.EvkCmplxToReal != nil {
-
-				if inc, err = buffer.WriteAsUint8[int](w, 1); err != nil {
-					return
-				}
-
-				n += inc
-
-				if inc, err = evk.Bootstrapping.EvkCmplxToReal.WriteTo(w); err != nil {
-					return
-				}
-
-				n += inc
-
-			} else {
-
-				if inc, err = buffer.WriteAsUint8[int](w, 0); err != nil {
-					return
-				}
-
-				n += inc
-			}
-
-			if evk.Bootstrapping.EvkDenseToSparse != nil {
-
-				if inc, err = buffer.WriteAsUint8[int](w, 1); err != nil {
-					return
-				}
-
-				n += inc
-
-				if inc, err = evk.Bootstrapping.EvkDenseToSparse.WriteTo(w); err != nil {
-					return
-				}
-
-				n += inc
-
-			} else {
-
-				if inc, err = buffer.WriteAsUint8[int](w, 0); err != nil {
-					return
-				}
-
-				n += inc
-			}
-
-			if evk.Bootstrapping.EvkSparseToDense != nil {
-
-				if inc, err = buffer.WriteAsUint8[int](w, 1); err != nil {
-					return
-				}
-
-				n += inc
-
-				if inc, err = evk.Bootstrapping.EvkSparseToDense.WriteTo(w); err != nil {
-					return
-				}
-
-				n += inc
-
-			} else {
-
-				if inc, err = buffer.WriteAsUint8[int](w, 0); err != nil {
-					return
-				}
-
-				n += inc
-			}
-
-			if evk.Bootstrapping.MemEvaluationKeySet != nil {
-
-				if inc, err = buffer.WriteAsUint8[int](w, 1); err != nil {
-					return
-				}
-
-				n += inc
-
-				if inc, err = evk.Bootstrapping.MemEvaluationKeySet.WriteTo(w); err != nil {
-					return
-				}
-
-				n += inc
-
-			} else {
-
-				if inc, err = buffer.WriteAsUint8[int](w, 0); err != nil {
-					return
-				}
-
-				n += inc
-			}
-		}else{
-			if inc, err = buffer.WriteAsUint8[int](w, 0); err != nil {
-				return
-			}
-
-			n += inc
-		}
-
-		return n, w.Flush()
+		return evk.writeIndexed(w)
 	default:
 		return evk.WriteTo(bufio.NewWriter(w))
 	}
@@ -282,175 +186,8 @@ func (evk EvaluationKeySet) WriteTo(w io.Writer) (n int64, err error) {
 func (evk *EvaluationKeySet) ReadFrom(r io.Reader) (n int64, err error) {
 	switch r := r.(type) {
 	case buffer.Reader:
-
-		var inc int64
-
-		var exist int
-
-		if inc, err = buffer.ReadAsUint8[int](r, &exist); err != nil {
-			return
-		}
-
-		n += inc
-
-		if exist == 1{
-			if evk.Scheme == nil{
-				evk.Scheme = &rlwe.MemEvaluationKeySet{}
-			}
-
-			if inc, err = evk.Scheme.ReadFrom(r); err != nil {
-				return
-			}
-
-			n += inc
-		}
-
-		if inc, err = buffer.ReadAsUint8[int](r, &exist); err != nil {
-			return
-		}
-
-		if exist == 1{
-
-			if evk.Bootstrapping == nil{
-				evk.Bootstrapping = &bootstrapping.EvaluationKeys{}
-			}
-
-			if inc, err = buffer.ReadAsUint8[int](r, &exist); err != nil {
-				return
-			}
-
-			n += inc
-
-			if exist == 1 {
-
-				if evk.Bootstrapping.EvkN1ToN2 == nil{
-					evk.Bootstrapping.EvkN1ToN2 = &rlwe.EvaluationKey{}
-				}
-
-				if inc, err = evk.Bootstrapping.EvkN1ToN2.ReadFrom(r); err != nil {
-					return
-				}
-
-				n += inc
-			}
-
-			if inc, err = buffer.ReadAsUint8[int](r, &exist); err != nil {
-				return
-			}
-
-			n += inc
-
-			if exist == 1 {
-
-				if evk.Bootstrapping.EvkN2ToN1 == nil{
-					evk.Bootstrapping.EvkN2ToN1 = &rlwe.EvaluationKey{}
-				}
-
-				if inc, err = evk.Bootstrapping.EvkN2ToN1.ReadFrom(r); err != nil {
-					return
-				}
-
-				n += inc
-			}
-
-			if inc, err = buffer.ReadAsUint8[int](r, &exist); err != nil {
-				return
-			}
-
-			n += inc
-
-			if exist == 1 {
-
-				if evk.Bootstrapping.EvkRealToCmplx == nil{
-					evk.Bootstrapping.EvkRealToCmplx = &rlwe.EvaluationKey{}
-				}
-
-				if inc, err = evk.Bootstrapping.EvkRealToCmplx.ReadFrom(r); err != nil {
-					return
-				}
-
-				n += inc
-			}
-
-			if inc, err = buffer.ReadAsUint8[int](r, &exist); err != nil {
-				return
-			}
-
-			n += inc
-
-			if exist == 1 {
-
-				if evk.Bootstrapping.EvkCmplxToReal == nil{
-					evk.Bootstrapping.EvkCmplxToReal = &rlwe.EvaluationKey{}
-				}
-
-				if inc, err = evk.Bootstrapping.EvkCmplxToReal.ReadFrom(r); err != nil {
-					return
-				}
-
-				n += inc
-			}
-
-			if inc, err = buffer.ReadAsUint8[int](r, &exist); err != nil {
-				return
-			}
-
-			n += inc
-
-			if exist == 1 {
-
-				if evk.Bootstrapping.EvkDenseToSparse == nil{
-					evk.Bootstrapping.EvkDenseToSparse = &rlwe.EvaluationKey{}
-				}
-
-				if inc, err = evk.Bootstrapping.EvkDenseToSparse.ReadFrom(r); err != nil {
-					return
-				}
-
-				n += inc
-			}
-
-			if inc, err = buffer.ReadAsUint8[int](r, &exist); err != nil {
-				return
-			}
-
-			n += inc
-
-			if exist == 1 {
-
-				if evk.Bootstrapping.EvkSparseToDense == nil{
-					evk.Bootstrapping.EvkSparseToDense = &rlwe.EvaluationKey{}
-				}
-
-				if inc, err = evk.Bootstrapping.EvkSparseToDense.ReadFrom(r); err != nil {
-					return
-				}
-
-				n += inc
-			}
-
-			if inc, err = buffer.ReadAsUint8[int](r, &exist); err != nil {
-				return
-			}
-
-			n += inc
-
-			if exist == 1 {
-
-				if evk.Bootstrapping.MemEvaluationKeySet == nil{
-					evk.Bootstrapping.MemEvaluationKeySet = &rlwe.MemEvaluationKeySet{}
-				}
-
-				if inc, err = evk.Bootstrapping.MemEvaluationKeySet.ReadFrom(r); err != nil {
-					return
-				}
-
-				n += inc
-			}
-		}
-
-		return n, nil
+		return evk.readIndexed(r)
 	default:
 		return evk.ReadFrom(bufio.NewReader(r))
 	}
-}
\ No newline at end of file
+}
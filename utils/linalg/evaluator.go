@@ -0,0 +1,174 @@
+package linalg
+
+import (
+	"fmt"
+
+	"github.com/tuneinsight/lattigo/v5/core/rlwe"
+	"github.com/tuneinsight/lattigo/v5/he"
+	"github.com/tuneinsight/lattigo/v5/he/hefloat"
+
+	"app/utils"
+)
+
+// Evaluator evaluates matrix products on ciphertexts.
+type Evaluator struct {
+	params hefloat.Parameters
+	eval   utils.Evaluator
+	ecd    *hefloat.Encoder
+
+	// lt is nil if eval cannot evaluate linear transformations, as the
+	// simulator, in which case the diagonals are evaluated with rotations.
+	lt *hefloat.LinearTransformationEvaluator
+}
+
+// NewEvaluator returns an Evaluator on top of the evaluator given to SolveTestcase.
+func NewEvaluator(params hefloat.Parameters, eval utils.Evaluator) *Evaluator {
+
+	e := &Evaluator{
+		params: params,
+		eval:   eval,
+		ecd:    hefloat.NewEncoder(params),
+	}
+
+	// The wrappers such as the profiling evaluator delegate the linear
+	// transformations to the evaluator they wrap
+	for inner := eval; inner != nil; {
+		if lt, ok := inner.(he.EvaluatorForLinearTransformation); ok {
+			e.lt = hefloat.NewLinearTransformationEvaluator(lt)
+			break
+		}
+		w, ok := inner.(interface{ Unwrap() utils.Evaluator })
+		if !ok {
+			break
+		}
+		inner = w.Unwrap()
+	}
+
+	return e
+}
+
+// Mul returns the product of the plaintext matrix m by the vector of ct,
+// replicated every m.Dim slots. It consumes one level.
+func (e Evaluator) Mul(m *Matrix, ct *rlwe.Ciphertext) (res *rlwe.Ciphertext, err error) {
+
+	if err = m.Check(e.params); err != nil {
+		return nil, fmt.Errorf("cannot Mul: %w", err)
+	}
+
+	if ct.Level() == 0 {
+		return nil, fmt.Errorf("cannot Mul: the ciphertext is at level 0")
+	}
+
+	defer utils.Begin(e.eval, "LinearTransformation")()
+
+	if e.lt != nil {
+
+		var lt hefloat.LinearTransformation
+		if lt, err = m.linearTransformation(e.params, e.ecd, ct.Level()); err != nil {
+			return nil, fmt.Errorf("cannot Mul: %w", err)
+		}
+
+		if res, err = e.lt.EvaluateNew(ct, lt); err != nil {
+			return nil, fmt.Errorf("cannot Mul: %T.EvaluateNew: %w", e.lt, err)
+		}
+
+	} else if res, err = e.mulDiagonals(m, ct); err != nil {
+		return nil, fmt.Errorf("cannot Mul: %w", err)
+	}
+
+	if err = e.eval.Rescale(res, res); err != nil {
+		return nil, fmt.Errorf("cannot Mul: %T.Rescale: %w", e.eval, err)
+	}
+
+	return
+}
+
+// mulDiagonals returns sum_k Diagonals[k] * Rotate(ct, k), before the rescale.
+func (e Evaluator) mulDiagonals(m *Matrix, ct *rlwe.Ciphertext) (res *rlwe.Ciphertext, err error) {
+
+	diagonals := m.extended(e.params.MaxSlots())
+
+	for _, k := range m.indexes() {
+
+		rot := ct
+		if k != 0 {
+			if rot, err = e.eval.RotateNew(ct, k); err != nil {
+				return nil, fmt.Errorf("%T.RotateNew: %w", e.eval, err)
+			}
+		}
+
+		var tmp *rlwe.Ciphertext
+		if tmp, err = e.eval.MulNew(rot, diagonals[k]); err != nil {
+			return nil, fmt.Errorf("%T.MulNew: %w", e.eval, err)
+		}
+
+		if res == nil {
+			res = tmp
+		} else if err = e.eval.Add(res, tmp, res); err != nil {
+			return nil, fmt.Errorf("%T.Add: %w", e.eval, err)
+		}
+	}
+
+	return
+}
+
+// Replicate returns the values of the first dim slots of ct, which must be
+// zero elsewhere, copied every dim slots, as expected by the products of the
+// package. dim must divide the number of slots. It consumes no level and uses
+// the rotations of utils.Reduction{Batch: dim}.
+func (e Evaluator) Replicate(ct *rlwe.Ciphertext, dim int) (res *rlwe.Ciphertext, err error) {
+
+	if slots := e.params.MaxSlots(); dim < 1 || slots%dim != 0 {
+		return nil, fmt.Errorf("cannot Replicate: %d does not divide the %d slots", dim, slots)
+	}
+
+	return utils.Reduction{Batch: dim}.Sum(e.eval, ct)
+}
+
+// MulVector returns the product of the encrypted rows x cols matrix of a,
+// packed row-major, by the encrypted vector of x, copied in each of the rows
+// blocks of cols slots (see Replicate). The i-th value of the result is in
+// the slot i*cols, or in all the slots of the i-th block with broadcast.
+// It consumes one level, or two with broadcast, and uses the rotations of
+// utils.Reduction{N: cols, Broadcast: broadcast}, see MulVectorGaloisElements.
+func (e Evaluator) MulVector(a, x *rlwe.Ciphertext, cols int, broadcast bool) (res *rlwe.Ciphertext, err error) {
+
+	if res, err = e.eval.MulRelinNew(a, x); err != nil {
+		return nil, fmt.Errorf("cannot MulVector: %T.MulRelinNew: %w", e.eval, err)
+	}
+
+	if err = e.eval.Rescale(res, res); err != nil {
+		return nil, fmt.Errorf("cannot MulVector: %T.Rescale: %w", e.eval, err)
+	}
+
+	if res, err = (utils.Reduction{N: cols, Broadcast: broadcast}).Sum(e.eval, res); err != nil {
+		return nil, fmt.Errorf("cannot MulVector: %w", err)
+	}
+
+	return
+}
+
+// ReplicateGaloisElements returns the Galois elements needed by Replicate.
+func ReplicateGaloisElements(params hefloat.Parameters, dim int) ([]uint64, error) {
+	return reductionGaloisElements(params, utils.Reduction{Batch: dim})
+}
+
+// MulVectorGaloisElements returns the Galois elements needed by MulVector.
+func MulVectorGaloisElements(params hefloat.Parameters, cols int, broadcast bool) ([]uint64, error) {
+	return reductionGaloisElements(params, utils.Reduction{N: cols, Broadcast: broadcast})
+}
+
+func reductionGaloisElements(params hefloat.Parameters, r utils.Reduction) ([]uint64, error) {
+
+	rotations, err := r.Rotations(params)
+	if err != nil {
+		return nil, err
+	}
+
+	galEls := make([]uint64, len(rotations))
+	for i, k := range rotations {
+		galEls[i] = params.GaloisElement(k)
+	}
+
+	return sorted(galEls), nil
+}
@@ -0,0 +1,215 @@
+// Package linalg evaluates matrix products on ciphertexts: plaintext matrix
+// times encrypted vector with hefloat.LinearTransformation, encrypted matrix
+// times encrypted vector packed in the slots, and encrypted matrix product
+// (Jiang et al., "Secure Outsourced Matrix Computation and Application to
+// Neural Networks", CCS 2018).
+//
+// A vector or a matrix of dimension Dim, a power of two dividing the number of
+// slots, is packed in the first Dim slots (row-major for a matrix) and is
+// replicated every Dim slots, so that a rotation of the ciphertext acts as a
+// cyclic rotation of each copy (see Evaluator.Replicate).
+package linalg
+
+import (
+	"fmt"
+	"math/bits"
+	"sort"
+	"sync"
+
+	"github.com/tuneinsight/lattigo/v5/core/rlwe"
+	"github.com/tuneinsight/lattigo/v5/he/hefloat"
+)
+
+// DefaultLogBSGSRatio is the log2 of the ratio between the giant and the baby
+// steps of the baby-step giant-step evaluation, as in the examples of Lattigo.
+const DefaultLogBSGSRatio = 1
+
+// Matrix is a plaintext square matrix in diagonal form, applied to vectors of
+// dimension Dim: (M x)[i] = sum_k Diagonals[k][i] * x[(i+k) % Dim].
+type Matrix struct {
+	Dim       int               // Power of two.
+	Diagonals map[int][]float64 // Non-zero diagonals, Diagonals[k][i] = M[i][(i+k) % Dim] for k in [0, Dim).
+
+	// LogBSGSRatio is the log2 of the ratio n1/n2 of the baby-step giant-step
+	// evaluation, which needs about 2*sqrt(#diagonals) rotations. If negative,
+	// the diagonals are evaluated one rotation each (Halevi–Shoup), which is
+	// faster for a few diagonals but needs one Galois key per diagonal.
+	// It must be set before the first product, which encodes the matrix.
+	LogBSGSRatio int
+
+	mu      sync.Mutex
+	encoded map[int]hefloat.LinearTransformation // By level.
+}
+
+// NewMatrix returns the diagonal form of the rows x cols matrix m, padded with
+// zeros to the smallest power of two Dim larger than rows and cols. The product
+// by a vector of length cols returns a vector of length rows, both padded to Dim.
+func NewMatrix(m [][]float64) (*Matrix, error) {
+
+	if len(m) == 0 || len(m[0]) == 0 {
+		return nil, fmt.Errorf("cannot NewMatrix: empty matrix")
+	}
+
+	rows, cols := len(m), len(m[0])
+	for i := range m {
+		if len(m[i]) != cols {
+			return nil, fmt.Errorf("cannot NewMatrix: row %d has %d columns instead of %d", i, len(m[i]), cols)
+		}
+	}
+
+	dim := 1 << bits.Len(uint(max(rows, cols)-1))
+
+	diagonals := map[int][]float64{}
+	for i := range m {
+		for j, v := range m[i] {
+			if v != 0 {
+				k := (j - i + dim) % dim
+				if diagonals[k] == nil {
+					diagonals[k] = make([]float64, dim)
+				}
+				diagonals[k][i] = v
+			}
+		}
+	}
+
+	return NewMatrixFromDiagonals(dim, diagonals)
+}
+
+// NewMatrixFromDiagonals returns the matrix of dimension dim with the given
+// diagonals, whose indexes are taken modulo dim.
+func NewMatrixFromDiagonals(dim int, diagonals map[int][]float64) (*Matrix, error) {
+
+	if dim < 1 || dim&(dim-1) != 0 {
+		return nil, fmt.Errorf("cannot NewMatrixFromDiagonals: dimension %d is not a power of two", dim)
+	}
+
+	m := &Matrix{
+		Dim:          dim,
+		Diagonals:    map[int][]float64{},
+		LogBSGSRatio: DefaultLogBSGSRatio,
+	}
+
+	for k, diag := range diagonals {
+
+		if len(diag) != dim {
+			return nil, fmt.Errorf("cannot NewMatrixFromDiagonals: diagonal %d has length %d instead of %d", k, len(diag), dim)
+		}
+
+		k = ((k % dim) + dim) % dim
+
+		if m.Diagonals[k] != nil {
+			return nil, fmt.Errorf("cannot NewMatrixFromDiagonals: diagonal %d is given twice modulo %d", k, dim)
+		}
+
+		m.Diagonals[k] = diag
+	}
+
+	if len(m.Diagonals) == 0 {
+		return nil, fmt.Errorf("cannot NewMatrixFromDiagonals: no diagonal")
+	}
+
+	return m, nil
+}
+
+// newPermutation returns the matrix of dimension dim moving the value of slot src(i) to slot i.
+func newPermutation(dim int, src func(i int) int) *Matrix {
+
+	diagonals := map[int][]float64{}
+	for i := 0; i < dim; i++ {
+		k := ((src(i)-i)%dim + dim) % dim
+		if diagonals[k] == nil {
+			diagonals[k] = make([]float64, dim)
+		}
+		diagonals[k][i] = 1
+	}
+
+	return &Matrix{Dim: dim, Diagonals: diagonals, LogBSGSRatio: DefaultLogBSGSRatio}
+}
+
+// Check returns an error if the matrix cannot be evaluated with the given parameters.
+func (m *Matrix) Check(params hefloat.Parameters) error {
+	if slots := params.MaxSlots(); m.Dim > slots || slots%m.Dim != 0 {
+		return fmt.Errorf("matrix dimension %d does not divide the %d slots", m.Dim, slots)
+	}
+	return nil
+}
+
+// GaloisElements returns the Galois elements needed to evaluate the matrix.
+func (m *Matrix) GaloisElements(params hefloat.Parameters) []uint64 {
+	return sorted(hefloat.GaloisElementsForLinearTransformation(params, m.parameters(params, params.MaxLevel())))
+}
+
+// indexes returns the sorted indexes of the diagonals.
+func (m *Matrix) indexes() (k []int) {
+	for i := range m.Diagonals {
+		k = append(k, i)
+	}
+	sort.Ints(k)
+	return
+}
+
+// parameters returns the parameters of the linear transformation acting on
+// all the slots, at the given level and at the scale of its modulus, so that
+// the rescale after the product restores the scale of the ciphertext.
+func (m *Matrix) parameters(params hefloat.Parameters, level int) hefloat.LinearTransformationParameters {
+	return hefloat.LinearTransformationParameters{
+		DiagonalsIndexList:       m.indexes(),
+		Level:                    level,
+		Scale:                    rlwe.NewScale(params.Q()[level]),
+		LogDimensions:            params.LogMaxDimensions(),
+		LogBabyStepGianStepRatio: m.LogBSGSRatio,
+	}
+}
+
+// linearTransformation returns the matrix encoded at the given level, encoding it on first use.
+func (m *Matrix) linearTransformation(params hefloat.Parameters, ecd *hefloat.Encoder, level int) (lt hefloat.LinearTransformation, err error) {
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if lt, ok := m.encoded[level]; ok {
+		return lt, nil
+	}
+
+	lt = hefloat.NewLinearTransformation(params, m.parameters(params, level))
+
+	if err = hefloat.EncodeLinearTransformation[float64](ecd, m.extended(params.MaxSlots()), lt); err != nil {
+		return lt, fmt.Errorf("hefloat.EncodeLinearTransformation: %w", err)
+	}
+
+	if m.encoded == nil {
+		m.encoded = map[int]hefloat.LinearTransformation{}
+	}
+
+	m.encoded[level] = lt
+
+	return
+}
+
+// extended returns the diagonals repeated over the given number of slots,
+// which acts as the matrix on every copy of a replicated vector.
+func (m *Matrix) extended(slots int) hefloat.Diagonals[float64] {
+	diagonals := hefloat.Diagonals[float64]{}
+	for k, diag := range m.Diagonals {
+		v := make([]float64, slots)
+		for i := range v {
+			v[i] = diag[i%m.Dim]
+		}
+		diagonals[k] = v
+	}
+	return diagonals
+}
+
+// sorted returns the sorted distinct elements of galEls, without the
+// identity, the rotation by 0, which needs no key.
+func sorted(galEls []uint64) (out []uint64) {
+	set := map[uint64]bool{1: true}
+	for _, galEl := range galEls {
+		if !set[galEl] {
+			set[galEl] = true
+			out = append(out, galEl)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i] < out[j] })
+	return
+}
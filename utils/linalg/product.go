@@ -0,0 +1,125 @@
+package linalg
+
+import (
+	"fmt"
+
+	"github.com/tuneinsight/lattigo/v5/core/rlwe"
+	"github.com/tuneinsight/lattigo/v5/he/hefloat"
+
+	"app/utils"
+)
+
+// Product is the encrypted product of d x d matrices of Jiang et al.:
+// AB = sum_k Phi^k(Sigma(A)) * Psi^k(Tau(B)) for k < d, with
+//
+//	Sigma(A)[i][j] = A[i][i+j], Tau(B)[i][j] = B[i+j][j],
+//	Phi(A)[i][j] = A[i][j+1] and Psi(B)[i][j] = B[i+1][j] (indexes modulo d).
+//
+// Psi^k is a rotation by k*d slots, and Sigma, Tau and Phi^k are plaintext
+// matrices of dimension d*d with at most 2d-1 diagonals.
+type Product struct {
+	D     int
+	Sigma *Matrix
+	Tau   *Matrix
+	Phi   []*Matrix // Phi^k for 0 < k < d, Phi[0] is nil.
+}
+
+// NewProduct returns the Product of d x d matrices, where d is a power of two.
+func NewProduct(d int) (*Product, error) {
+
+	if d < 1 || d&(d-1) != 0 {
+		return nil, fmt.Errorf("cannot NewProduct: dimension %d is not a power of two", d)
+	}
+
+	dim := d * d
+
+	p := &Product{
+		D: d,
+		Sigma: newPermutation(dim, func(l int) int {
+			i, j := l/d, l%d
+			return i*d + (i+j)%d
+		}),
+		Tau: newPermutation(dim, func(l int) int {
+			i, j := l/d, l%d
+			return ((i+j)%d)*d + j
+		}),
+		Phi: make([]*Matrix, d),
+	}
+
+	for k := 1; k < d; k++ {
+		p.Phi[k] = newPermutation(dim, func(l int) int {
+			i, j := l/d, l%d
+			return i*d + (j+k)%d
+		})
+		// Two diagonals, k and k-d, are evaluated faster one rotation each
+		p.Phi[k].LogBSGSRatio = -1
+	}
+
+	return p, nil
+}
+
+// GaloisElements returns the Galois elements needed by Evaluator.MulMatrices.
+func (p *Product) GaloisElements(params hefloat.Parameters) []uint64 {
+
+	galEls := append(p.Sigma.GaloisElements(params), p.Tau.GaloisElements(params)...)
+
+	for k := 1; k < p.D; k++ {
+		galEls = append(galEls, p.Phi[k].GaloisElements(params)...)
+		galEls = append(galEls, params.GaloisElement(k*p.D))
+	}
+
+	return sorted(galEls)
+}
+
+// MulMatrices returns the product of the encrypted d x d matrices of a and b,
+// packed row-major and replicated every d*d slots (see Replicate), in the same
+// format. It consumes three levels, two if d = 1.
+func (e Evaluator) MulMatrices(p *Product, a, b *rlwe.Ciphertext) (res *rlwe.Ciphertext, err error) {
+
+	defer utils.Begin(e.eval, "MulMatrices")()
+
+	var a0, b0 *rlwe.Ciphertext
+	if a0, err = e.Mul(p.Sigma, a); err != nil {
+		return nil, fmt.Errorf("cannot MulMatrices: Sigma: %w", err)
+	}
+
+	if b0, err = e.Mul(p.Tau, b); err != nil {
+		return nil, fmt.Errorf("cannot MulMatrices: Tau: %w", err)
+	}
+
+	for k := 0; k < p.D; k++ {
+
+		// Phi^k consumes a level, Phi^0 is the identity
+		ak, bk := a0, b0
+		if k == 0 && p.D > 1 {
+			ak, bk = e.eval.DropLevelNew(a0, 1), e.eval.DropLevelNew(b0, 1)
+		} else if k > 0 {
+
+			if ak, err = e.Mul(p.Phi[k], a0); err != nil {
+				return nil, fmt.Errorf("cannot MulMatrices: Phi^%d: %w", k, err)
+			}
+
+			if bk, err = e.eval.RotateNew(b0, k*p.D); err != nil {
+				return nil, fmt.Errorf("cannot MulMatrices: %T.RotateNew: %w", e.eval, err)
+			}
+		}
+
+		if res == nil {
+			if res, err = e.eval.MulNew(ak, bk); err != nil {
+				return nil, fmt.Errorf("cannot MulMatrices: %T.MulNew: %w", e.eval, err)
+			}
+		} else if err = e.eval.MulThenAdd(ak, bk, res); err != nil {
+			return nil, fmt.Errorf("cannot MulMatrices: %T.MulThenAdd: %w", e.eval, err)
+		}
+	}
+
+	if err = e.eval.Relinearize(res, res); err != nil {
+		return nil, fmt.Errorf("cannot MulMatrices: %T.Relinearize: %w", e.eval, err)
+	}
+
+	if err = e.eval.Rescale(res, res); err != nil {
+		return nil, fmt.Errorf("cannot MulMatrices: %T.Rescale: %w", e.eval, err)
+	}
+
+	return
+}
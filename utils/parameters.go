@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"bufio"
 	"encoding/json"
+	"crypto/sha256"
 
 	"github.com/tuneinsight/lattigo/v5/he/hefloat"
 	"github.com/tuneinsight/lattigo/v5/he/hefloat/bootstrapping"
@@ -17,6 +18,12 @@ type Parameters struct {
 	btpLiteral bootstrappingParametersLiteral
 }
 
+// Hash returns the SHA-256 digest of the JSON encoding of the parameters.
+func (p Parameters) Hash() [sha256.Size]byte {
+	data, _ := p.MarshalJSON()
+	return sha256.Sum256(data)
+}
+
 func (p Parameters) BinarySize() int {
 	data, _ := p.MarshalJSON()
 	return len(data) + 4
@@ -61,8 +68,8 @@ func (p *Parameters) ReadFrom(r io.Reader) (n int64, err error) {
 		bytes := make([]byte, size)
 
 		var inc int
-		if inc, err = r.Read(bytes); err != nil {
-			return n + int64(inc), fmt.Errorf("io.Reader.Read: %w", err)
+		if inc, err = io.ReadFull(r, bytes); err != nil {
+			return n + int64(inc), fmt.Errorf("io.ReadFull: %w", err)
 		}
 
 		return n + int64(inc), p.UnmarshalJSON(bytes)
@@ -0,0 +1,224 @@
+package utils
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/tuneinsight/lattigo/v5/core/rlwe"
+	"github.com/tuneinsight/lattigo/v5/ring"
+	"github.com/tuneinsight/lattigo/v5/utils"
+)
+
+const (
+	// paramGenMinLogN and paramGenMaxLogN bound the ring degrees tried by GenParameters.
+	paramGenMinLogN = 10
+	paramGenMaxLogN = 17
+
+	// paramGenLogP is the bit size of the auxiliary primes.
+	paramGenLogP = 61
+
+	// paramGenMaxLogQ0 is the bit size cap of the first prime, and paramGenMaxLogScale
+	// the cap of the default scale, which leaves room for messages up to 2^10 at level 0.
+	paramGenMaxLogQ0    = 60
+	paramGenMaxLogScale = 50
+
+	// paramGenMaxBootstrappingPrecision is the precision of the default bootstrapping
+	// with a sparse secret (H=192), and paramGenMaxDenseBootstrappingPrecision with a
+	// dense secret (see the comments of bootstrapping.DefaultParametersSparse/Dense).
+	paramGenMaxBootstrappingPrecision      = 26
+	paramGenMaxDenseBootstrappingPrecision = 23
+)
+
+// ParametersTarget is the input of GenParameters.
+type ParametersTarget struct {
+	Depth         int       // Number of rescalings of the circuit (between two bootstrappings if Bootstrapping is set)
+	Precision     float64   // Target precision in bits
+	Bootstrapping bool      // Whether the circuit bootstraps
+	Security      float64   // Minimum bit security, DefaultMinSecurity if zero
+	LogSlots      int       // Minimum log2 number of slots, optional
+	RingType      ring.Type // ring.Standard (complex slots) or ring.ConjugateInvariant (real slots)
+}
+
+// SchemeLiteral is the "Scheme" block of config.json generated by GenParameters.
+type SchemeLiteral struct {
+	LogN            int
+	LogQ            []int
+	LogP            []int
+	Xs              ring.DistributionParameters `json:",omitempty"`
+	RingType        ring.Type
+	LogDefaultScale int
+}
+
+// ParametersConfig holds the "Scheme" and "Bootstrapping" blocks of config.json.
+type ParametersConfig struct {
+	Scheme        SchemeLiteral
+	Bootstrapping bootstrappingParametersLiteral
+}
+
+// Parameters instantiates the parameters described by the configuration.
+func (cfg ParametersConfig) Parameters() (params Parameters, err error) {
+
+	var data []byte
+	if data, err = json.Marshal(cfg); err != nil {
+		return
+	}
+
+	err = params.UnmarshalJSON(data)
+
+	return
+}
+
+// paramGenCandidate is a set of parameters considered by GenParameters.
+type paramGenCandidate struct {
+	cfg  ParametersConfig
+	cost float64
+}
+
+// GenParameters returns the configuration of the parameters meeting the target
+// depth, precision and security with the smallest evaluation keys, and the
+// corresponding parameters.
+//
+// The default scale is the target precision plus LogN/2 + 10 bits of margin
+// for the encryption and rescaling noise, the first prime is 15 bits larger
+// (60 bits with bootstrapping), and there is one prime per level of the size
+// of the scale. The auxiliary modulus is made of k primes of 61 bits, which
+// keeps the key-switching noise below the rounding noise. Over the ring degrees
+// and values of k meeting the security, GenParameters minimizes the size of an
+// evaluation key, N * ceil(#Qi/k) * (#Qi + k), which is also the cost of a
+// key-switching. With bootstrapping, the bootstrapping parameters are the
+// defaults of Lattigo, in the same ring as the scheme parameters (twice the
+// degree for ring.ConjugateInvariant).
+func GenParameters(target ParametersTarget) (cfg ParametersConfig, params Parameters, err error) {
+
+	if target.Depth < 0 {
+		return cfg, params, fmt.Errorf("invalid target: Depth=%d is negative", target.Depth)
+	}
+
+	if target.Precision <= 0 {
+		return cfg, params, fmt.Errorf("invalid target: Precision=%v is not positive", target.Precision)
+	}
+
+	if target.Security == 0 {
+		target.Security = DefaultMinSecurity
+	}
+
+	var xs ring.DistributionParameters
+	if target.Bootstrapping {
+		switch {
+		case target.Precision <= paramGenMaxDenseBootstrappingPrecision:
+		case target.Precision <= paramGenMaxBootstrappingPrecision:
+			xs = ring.Ternary{H: 192}
+		default:
+			return cfg, params, fmt.Errorf("invalid target: the default bootstrapping provides at most %d bits of precision, but Precision=%v (set Bootstrapping.IterationsParameters by hand)", paramGenMaxBootstrappingPrecision, target.Precision)
+		}
+	}
+
+	var candidates []paramGenCandidate
+
+	for logN := paramGenMinLogN; logN <= paramGenMaxLogN; logN++ {
+
+		maxLogSlots := logN - 1
+		if target.RingType == ring.ConjugateInvariant {
+			maxLogSlots = logN
+		}
+
+		if target.LogSlots > maxLogSlots {
+			continue
+		}
+
+		logScale := int(math.Ceil(target.Precision)) + (logN+1)/2 + 10
+		if logScale > paramGenMaxLogScale {
+			continue
+		}
+
+		logQ0 := utils.Min(logScale+15, paramGenMaxLogQ0)
+		if target.Bootstrapping {
+			logQ0 = paramGenMaxLogQ0
+		}
+
+		logQ := make([]int, target.Depth+1)
+		logQ[0] = logQ0
+		for i := 1; i < len(logQ); i++ {
+			logQ[i] = logScale
+		}
+
+		sumLogQ := 0
+		for _, v := range logQ {
+			sumLogQ += v
+		}
+
+		for k := 1; k <= len(logQ); k++ {
+
+			// Filters out the parameters that are insecure before instantiating them
+			if bits, _, err := EstimateSecurity(logN, float64(sumLogQ+k*paramGenLogP), schemeXs(xs)); err != nil || bits < target.Security {
+				continue
+			}
+
+			logP := make([]int, k)
+			for i := range logP {
+				logP[i] = paramGenLogP
+			}
+
+			c := paramGenCandidate{
+				cfg: ParametersConfig{
+					Scheme: SchemeLiteral{
+						LogN:            logN,
+						LogQ:            logQ,
+						LogP:            logP,
+						Xs:              xs,
+						RingType:        target.RingType,
+						LogDefaultScale: logScale,
+					},
+				},
+				cost: float64(int(1)<<logN) * float64((len(logQ)+k-1)/k) * float64(len(logQ)+k),
+			}
+
+			if target.Bootstrapping {
+				btpLogN := logN
+				if target.RingType == ring.ConjugateInvariant {
+					btpLogN++
+				}
+				c.cfg.Bootstrapping = bootstrappingParametersLiteral{
+					Enable:   true,
+					LogN:     utils.Pointy(btpLogN),
+					LogSlots: utils.Pointy(maxLogSlots),
+				}
+			}
+
+			candidates = append(candidates, c)
+		}
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].cost < candidates[j].cost
+	})
+
+	for _, c := range candidates {
+
+		if params, err = c.cfg.Parameters(); err != nil {
+			continue
+		}
+
+		if _, err = params.CheckSecurity(target.Security); err != nil {
+			continue
+		}
+
+		return c.cfg, params, nil
+	}
+
+	if err != nil {
+		return cfg, params, fmt.Errorf("no parameters meet the target %+v, last candidate: %w", target, err)
+	}
+
+	return cfg, params, fmt.Errorf("no parameters meet the target %+v", target)
+}
+
+// schemeXs returns the secret distribution of the scheme parameters, where nil stands for the default.
+func schemeXs(xs ring.DistributionParameters) ring.DistributionParameters {
+	if xs == nil {
+		return rlwe.DefaultXs
+	}
+	return xs
+}
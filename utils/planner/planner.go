@@ -0,0 +1,249 @@
+// Package planner plans the levels of a circuit before evaluating it: it
+// computes the levels consumed by each stage, inserts the bootstrappings
+// where the levels run out, and reports how many primes LogQ is missing
+// when the circuit does not fit without bootstrapping.
+package planner
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/bits"
+	"strings"
+
+	"app/utils"
+)
+
+// Types of Stage.
+const (
+	Mul       = "mul"       // A multiplication followed by a rescale, or a multiplication by a non-integer constant.
+	Poly      = "poly"      // A polynomial evaluated with hefloat.PolynomialEvaluator, ceil(log2(Degree+1)) levels.
+	Linear    = "linear"    // A plaintext linear transformation, e.g. linalg.Evaluator.Mul.
+	Levels    = "levels"    // Any sub-circuit consuming Levels levels, e.g. approx.Approximation.Depth.
+	Bootstrap = "bootstrap" // An explicit bootstrapping.
+)
+
+// Stage is a step of a circuit.
+type Stage struct {
+	Name   string `json:",omitempty"`
+	Type   string
+	Degree int `json:",omitempty"` // Degree of the polynomial of a Poly stage.
+	Levels int `json:",omitempty"` // Levels consumed by a Levels stage.
+	Repeat int `json:",omitempty"` // Number of times the stage is repeated, 1 if 0.
+}
+
+// Depth returns the number of rescalings of the stage, once, or an error if its type is unknown.
+func (s Stage) Depth() (int, error) {
+	switch s.Type {
+	case Mul, Linear:
+		return 1, nil
+	case Poly:
+		if s.Degree < 1 {
+			return 0, fmt.Errorf("invalid degree %d", s.Degree)
+		}
+		return bits.Len(uint(s.Degree)), nil
+	case Levels:
+		if s.Levels < 0 {
+			return 0, fmt.Errorf("invalid number of levels %d", s.Levels)
+		}
+		return s.Levels, nil
+	case Bootstrap:
+		return 0, nil
+	default:
+		return 0, fmt.Errorf("unknown type %q, must be %q, %q, %q, %q or %q", s.Type, Mul, Poly, Linear, Levels, Bootstrap)
+	}
+}
+
+// String returns the name of the stage, or a description of it.
+func (s Stage) String() string {
+	if s.Name != "" {
+		return s.Name
+	}
+	switch s.Type {
+	case Poly:
+		return fmt.Sprintf("poly degree %d", s.Degree)
+	case Levels:
+		return fmt.Sprintf("%d levels", s.Levels)
+	default:
+		return s.Type
+	}
+}
+
+// Circuit is a sequence of stages evaluated on a ciphertext.
+type Circuit []Stage
+
+// CircuitFromJSON reads a circuit given as a JSON array of stages.
+func CircuitFromJSON(data []byte) (c Circuit, err error) {
+	if err = json.Unmarshal(data, &c); err != nil {
+		return nil, fmt.Errorf("json.Unmarshal: %w", err)
+	}
+	return
+}
+
+// Step is a stage of the planned circuit.
+type Step struct {
+	Stage
+	Iteration int  // Iteration of the stage if it is repeated, from 0.
+	Inserted  bool // The step is a bootstrapping inserted by the planner.
+	LevelIn   int
+	LevelOut  int
+}
+
+// Plan is the timeline of the levels of a circuit.
+type Plan struct {
+	MaxLevel           int    // Level of the input and of the output of a bootstrapping.
+	MinInputLevel      int    // Minimum level of the input of a bootstrapping, -1 without bootstrapping.
+	BootstrappingDepth int    // Levels of the bootstrapping circuit, on top of the scheme modulus.
+	Steps              []Step // The steps running out of levels have a negative LevelOut.
+	Depth              int    // Levels consumed by the stages, bootstrapping excluded.
+	Bootstraps         int
+	Missing            int // Levels missing to evaluate the circuit, 0 if it fits.
+}
+
+// New plans the circuit on a ciphertext at the maximum level of params.
+// With bootstrapping, a bootstrapping is inserted before every stage that
+// would otherwise run out of levels, and the stages keep the minimum input
+// level of the bootstrapping for the next one, except the last stage.
+// Without bootstrapping, the plan records how many levels are missing.
+func New(params utils.Parameters, c Circuit) (p Plan, err error) {
+
+	perRescale := params.Scheme.LevelsConsumedPerRescaling()
+
+	p.MaxLevel = params.Scheme.MaxLevel()
+	p.MinInputLevel = -1
+	if params.Bootstrapping != nil {
+		p.MinInputLevel = params.Bootstrapping.BootstrappingParameters.LevelsConsumedPerRescaling()
+		p.BootstrappingDepth = params.Bootstrapping.BootstrappingParameters.MaxLevel() - params.Bootstrapping.ResidualParameters.MaxLevel()
+	}
+
+	var total int
+	for i, s := range c {
+		if _, err = s.Depth(); err != nil {
+			return p, fmt.Errorf("stage %d (%s): %w", i, s, err)
+		}
+		total += max(s.Repeat, 1)
+	}
+
+	level := p.MaxLevel
+	var n int
+	for _, s := range c {
+
+		depth, _ := s.Depth()
+		depth *= perRescale
+
+		for it := 0; it < max(s.Repeat, 1); it++ {
+
+			n++
+
+			if s.Type == Bootstrap {
+				if err = p.bootstrap(&level, s, it, false); err != nil {
+					return
+				}
+				continue
+			}
+
+			// Keeps the input level of the next bootstrapping, unless it is the last stage
+			reserve := 0
+			if p.MinInputLevel >= 0 && n < total {
+				reserve = p.MinInputLevel
+			}
+
+			if level-depth < reserve && p.MinInputLevel >= 0 {
+
+				if p.MaxLevel-depth < reserve {
+					return p, fmt.Errorf("stage %s consumes %d levels but a bootstrapping only provides %d, %d of which are kept for the next bootstrapping: split it", s, depth, p.MaxLevel, reserve)
+				}
+
+				if err = p.bootstrap(&level, Stage{Name: "bootstrap", Type: Bootstrap}, 0, true); err != nil {
+					return
+				}
+			}
+
+			p.Steps = append(p.Steps, Step{Stage: s, Iteration: it, LevelIn: level, LevelOut: level - depth})
+			p.Depth += depth
+			level -= depth
+
+			if level < 0 {
+				p.Missing = max(p.Missing, -level)
+			}
+		}
+	}
+
+	return
+}
+
+// bootstrap appends a bootstrapping to the plan.
+func (p *Plan) bootstrap(level *int, s Stage, it int, inserted bool) error {
+
+	if p.MinInputLevel < 0 {
+		return fmt.Errorf("stage %s is a bootstrapping but bootstrapping is not enabled", s)
+	}
+
+	if *level < p.MinInputLevel {
+		return fmt.Errorf("stage %s bootstraps a ciphertext at level %d below the minimum input level %d", s, *level, p.MinInputLevel)
+	}
+
+	p.Steps = append(p.Steps, Step{Stage: s, Iteration: it, Inserted: inserted, LevelIn: *level, LevelOut: p.MaxLevel})
+	p.Bootstraps++
+	*level = p.MaxLevel
+
+	return nil
+}
+
+// OutputLevel returns the level of the output of the circuit, negative if levels are missing.
+func (p Plan) OutputLevel() int {
+	if len(p.Steps) == 0 {
+		return p.MaxLevel
+	}
+	return p.Steps[len(p.Steps)-1].LevelOut
+}
+
+// Err returns an error if levels are missing.
+func (p Plan) Err() error {
+	if p.Missing > 0 {
+		return fmt.Errorf("the circuit consumes %d levels but the parameters provide %d: LogQ needs %d more primes, or enable bootstrapping", p.Depth, p.MaxLevel, p.Missing)
+	}
+	return nil
+}
+
+// String returns the timeline of the levels of the plan.
+func (p Plan) String() string {
+
+	var sb strings.Builder
+
+	if p.MinInputLevel >= 0 {
+		fmt.Fprintf(&sb, "bootstrapping: input level >= %d, output level %d, %d levels of its own\n", p.MinInputLevel, p.MaxLevel, p.BootstrappingDepth)
+	}
+
+	fmt.Fprintf(&sb, "%-32s %6s %8s\n", "STAGE", "DEPTH", "LEVEL")
+	fmt.Fprintf(&sb, "%-32s %6s %8d\n", "input", "", p.MaxLevel)
+
+	for _, s := range p.Steps {
+
+		name := s.Stage.String()
+		if s.Repeat > 1 {
+			name = fmt.Sprintf("%s [%d/%d]", name, s.Iteration+1, s.Repeat)
+		}
+
+		if s.Inserted {
+			name += " (inserted)"
+		}
+
+		depth := ""
+		if s.Type != Bootstrap {
+			depth = fmt.Sprintf("%d", s.LevelIn-s.LevelOut)
+		}
+
+		fmt.Fprintf(&sb, "%-32s %6s %3d -> %d", name, depth, s.LevelIn, s.LevelOut)
+		if s.LevelOut < 0 {
+			sb.WriteString("  OUT OF LEVELS")
+		}
+		sb.WriteString("\n")
+	}
+
+	fmt.Fprintf(&sb, "depth %d, %d bootstrapping(s), output level %d", p.Depth, p.Bootstraps, p.OutputLevel())
+	if err := p.Err(); err != nil {
+		fmt.Fprintf(&sb, "\n%s", err)
+	}
+
+	return sb.String()
+}
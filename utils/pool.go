@@ -0,0 +1,154 @@
+package utils
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+	"sync"
+
+	"github.com/tuneinsight/lattigo/v5/core/rlwe"
+	"github.com/tuneinsight/lattigo/v5/he"
+)
+
+// Worker is a worker of a Pool, run by a single goroutine at a time.
+type Worker struct {
+	Index        int
+	Evaluator    Evaluator                        // Shallow copy of the evaluator of the Pool.
+	Bootstrapper he.Bootstrapper[rlwe.Ciphertext] // Shallow copy of the bootstrapper of the Pool wrapped by Evaluator, nil if it has none.
+}
+
+// Pool evaluates independent pipelines, e.g. one per ciphertext of a vector,
+// concurrently on workers holding their own shallow copies of an evaluator
+// and of a bootstrapper. The results do not depend on the scheduling: the
+// pipeline i writes the i-th result, and the error returned is the one of the
+// first pipeline failing.
+//
+// Each copy has its own buffers, of about a dozen ciphertexts for an evaluator
+// and more for a bootstrapper, which the parallelism multiplies. The workers
+// are created on first use, no more than the pipelines of a call to Map.
+// The methods of a Pool must not be called concurrently. On top of a
+// Sequential evaluator, such as the simulator, a Pool has a single worker.
+type Pool struct {
+	Parallelism int // Maximum number of workers.
+
+	eval    Evaluator
+	btp     he.Bootstrapper[rlwe.Ciphertext]
+	workers []Worker
+}
+
+// NewPool returns a Pool of parallelism workers, runtime.GOMAXPROCS(0) if
+// parallelism is not positive, on top of the evaluator given to SolveTestcase
+// and of the bootstrapper btp, which can be nil. The copies of btp are wrapped
+// by the copies of eval, see WrapBootstrapper.
+func NewPool(eval Evaluator, btp he.Bootstrapper[rlwe.Ciphertext], parallelism int) *Pool {
+
+	if parallelism <= 0 {
+		parallelism = runtime.GOMAXPROCS(0)
+	}
+
+	return &Pool{Parallelism: parallelism, eval: eval, btp: UnwrapBootstrapper(btp)}
+}
+
+// grow creates the workers up to n.
+func (p *Pool) grow(n int) (err error) {
+
+	for i := len(p.workers); i < n; i++ {
+
+		w := Worker{Index: i}
+
+		if w.Evaluator, err = ShallowCopy(p.eval); err != nil {
+			return
+		}
+
+		if w.Bootstrapper, err = ShallowCopyBootstrapper(p.btp); err != nil {
+			return
+		}
+
+		w.Bootstrapper = WrapBootstrapper(w.Evaluator, w.Bootstrapper)
+
+		p.workers = append(p.workers, w)
+	}
+
+	return
+}
+
+// Map evaluates f(w, i) for i in [0, n) on the workers. The indexes are handed
+// out in increasing order and, once f fails on an index, the larger indexes are
+// not started: the error returned, that of the smallest failing index, does not
+// depend on the scheduling. Map also stops handing out indexes once ctx is done.
+// f must only use the evaluator and the bootstrapper of w.
+func (p *Pool) Map(ctx context.Context, n int, f func(w Worker, i int) error) error {
+
+	workers := min(max(p.Parallelism, 1), n)
+	if s, ok := find[Sequential](p.eval); ok && s.Sequential() {
+		workers = min(workers, 1)
+	}
+	if err := p.grow(workers); err != nil {
+		return fmt.Errorf("cannot Map: %w", err)
+	}
+
+	defer Begin(p.eval, "Pool")()
+
+	var mu sync.Mutex
+	next, failed := 0, n
+	errs := make([]error, n)
+
+	var wg sync.WaitGroup
+	for _, w := range p.workers[:workers] {
+
+		wg.Add(1)
+
+		go func(w Worker) {
+
+			defer wg.Done()
+
+			for {
+
+				mu.Lock()
+				i := next
+				if i >= n || i > failed || ctx.Err() != nil {
+					mu.Unlock()
+					return
+				}
+				next++
+				mu.Unlock()
+
+				if err := f(w, i); err != nil {
+					mu.Lock()
+					errs[i] = err
+					failed = min(failed, i)
+					mu.Unlock()
+				}
+			}
+		}(w)
+	}
+
+	wg.Wait()
+
+	if failed < n {
+		return fmt.Errorf("pipeline %d: %w", failed, errs[failed])
+	}
+
+	if next < n {
+		return ctx.Err()
+	}
+
+	return nil
+}
+
+// MapVector returns the vector of f(w, ct) for the ciphertexts ct of v, evaluated with Map.
+func (p *Pool) MapVector(ctx context.Context, v CiphertextVector, f func(w Worker, ct *rlwe.Ciphertext) (*rlwe.Ciphertext, error)) (res CiphertextVector, err error) {
+
+	res = make(CiphertextVector, len(v))
+
+	err = p.Map(ctx, len(v), func(w Worker, i int) (err error) {
+		res[i], err = f(w, v[i])
+		return
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return
+}
@@ -0,0 +1,278 @@
+package utils
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/tuneinsight/lattigo/v5/core/rlwe"
+	"github.com/tuneinsight/lattigo/v5/he/hefloat"
+)
+
+// Reduction is a rotate-and-combine reduction over the slots: the result in
+// slot j combines the N values of the slots j, j+Batch, ..., j+(N-1)*Batch
+// (cyclically), in about 2*log2(N) rotations and log2(N) sequential combinations.
+//
+// The slots are seen as consecutive blocks of N*Batch slots, each made of
+// Batch interleaved sub-blocks of N values: Batch = 1 reduces consecutive
+// slots (e.g. the rows of a row-major matrix of N columns) and Batch = w
+// reduces slots spaced by w (e.g. its columns). Only the first Batch slots of
+// a block hold its results, unless N*Batch is the number of slots (the
+// default), in which case every slot does, or Broadcast is set, in which case
+// the slots past the last complete block are zeroed.
+//
+// A Reduction can be listed in the "EvaluationKeys" block of config.json, in
+// which case NewEvaluationKeySet generates the Galois keys it needs.
+type Reduction struct {
+	Batch     int  `json:",omitempty"` // Distance between two reduced slots, 1 if 0.
+	N         int  `json:",omitempty"` // Number of reduced slots, all the slots/Batch if 0.
+	Broadcast bool `json:",omitempty"` // Copies the results to every slot of their block, for one more level.
+}
+
+// Dimensions returns the batch and number of values of the reduction, with their defaults.
+func (r Reduction) Dimensions(params hefloat.Parameters) (batch, n int, err error) {
+
+	slots := params.MaxSlots()
+
+	if batch = r.Batch; batch == 0 {
+		batch = 1
+	}
+
+	if batch < 0 || batch > slots {
+		return 0, 0, fmt.Errorf("invalid reduction: Batch=%d must be in [1, %d]", r.Batch, slots)
+	}
+
+	if n = r.N; n == 0 {
+		n = slots / batch
+	}
+
+	if n < 0 || n*batch > slots {
+		return 0, 0, fmt.Errorf("invalid reduction: N=%d must be in [1, %d] for Batch=%d", r.N, slots/batch, batch)
+	}
+
+	return
+}
+
+// broadcasts returns true if the results must be copied to every slot of their block.
+func (r Reduction) broadcasts(params hefloat.Parameters, batch, n int) bool {
+	return r.Broadcast && n*batch != params.MaxSlots()
+}
+
+// Rotations returns the sorted rotations used by the reduction, whose
+// Galois keys must be generated.
+func (r Reduction) Rotations(params hefloat.Parameters) (rotations []int, err error) {
+
+	batch, n, err := r.Dimensions(params)
+	if err != nil {
+		return
+	}
+
+	rots := map[int]bool{}
+
+	record := func(x, k int) (int, error) {
+		rots[k] = true
+		return x, nil
+	}
+
+	combine := func(a, b int) (int, error) {
+		return a, nil
+	}
+
+	if _, err = reduce(0, batch, n, record, combine); err != nil {
+		return
+	}
+
+	if r.broadcasts(params, batch, n) {
+		if _, err = reduce(0, -batch, n, record, combine); err != nil {
+			return
+		}
+	}
+
+	for k := range rots {
+		rotations = append(rotations, k)
+	}
+
+	sort.Ints(rotations)
+
+	return
+}
+
+// Reduce returns the reduction of ct with combine, which must return a new
+// ciphertext and not modify its operands, e.g. AddNew.
+func (r Reduction) Reduce(eval Evaluator, ct *rlwe.Ciphertext, combine func(a, b *rlwe.Ciphertext) (*rlwe.Ciphertext, error)) (res *rlwe.Ciphertext, err error) {
+	return r.reduce(eval, ct, combine, 1)
+}
+
+// reduce returns the reduction of ct with combine, multiplied by weight when
+// the results are broadcast, which saves the level of a later multiplication.
+func (r Reduction) reduce(eval Evaluator, ct *rlwe.Ciphertext, combine func(a, b *rlwe.Ciphertext) (*rlwe.Ciphertext, error), weight float64) (res *rlwe.Ciphertext, err error) {
+
+	params := *eval.GetParameters()
+
+	batch, n, err := r.Dimensions(params)
+	if err != nil {
+		return
+	}
+
+	defer Begin(eval, "Reduction")()
+
+	rotate := func(x *rlwe.Ciphertext, k int) (*rlwe.Ciphertext, error) {
+		return eval.RotateNew(x, k)
+	}
+
+	if res, err = reduce(ct.CopyNew(), batch, n, rotate, combine); err != nil {
+		return nil, fmt.Errorf("cannot Reduce: %w", err)
+	}
+
+	if !r.broadcasts(params, batch, n) {
+		return
+	}
+
+	// Keeps the first Batch slots of every block, and sums their copies
+	// shifted by 0, Batch, ..., (N-1)*Batch to fill the block. A last
+	// incomplete block would wrap around the first one, and is zeroed.
+	size := n * batch
+	mask := make([]float64, params.MaxSlots())
+	for j := range mask {
+		if j%size < batch && j-j%size+size <= len(mask) {
+			mask[j] = weight
+		}
+	}
+
+	if err = eval.Mul(res, mask, res); err != nil {
+		return nil, fmt.Errorf("cannot Reduce: %T.Mul: %w", eval, err)
+	}
+
+	if err = eval.Rescale(res, res); err != nil {
+		return nil, fmt.Errorf("cannot Reduce: %T.Rescale: %w", eval, err)
+	}
+
+	if res, err = reduce(res, -batch, n, rotate, adder(eval)); err != nil {
+		return nil, fmt.Errorf("cannot Reduce: broadcast: %w", err)
+	}
+
+	return
+}
+
+// Sum returns the sum of the reduced values. It consumes no level, or one with Broadcast.
+func (r Reduction) Sum(eval Evaluator, ct *rlwe.Ciphertext) (res *rlwe.Ciphertext, err error) {
+	return r.Reduce(eval, ct, adder(eval))
+}
+
+// Mean returns the mean of the reduced values. It consumes one level.
+func (r Reduction) Mean(eval Evaluator, ct *rlwe.Ciphertext) (res *rlwe.Ciphertext, err error) {
+
+	params := *eval.GetParameters()
+
+	batch, n, err := r.Dimensions(params)
+	if err != nil {
+		return
+	}
+
+	// The division by N is merged with the mask of the broadcast
+	if r.broadcasts(params, batch, n) {
+		return r.reduce(eval, ct, adder(eval), 1/float64(n))
+	}
+
+	if res, err = r.Sum(eval, ct); err != nil || n == 1 {
+		return
+	}
+
+	if err = eval.Mul(res, 1/float64(n), res); err != nil {
+		return nil, fmt.Errorf("%T.Mul: %w", eval, err)
+	}
+
+	if err = eval.Rescale(res, res); err != nil {
+		return nil, fmt.Errorf("%T.Rescale: %w", eval, err)
+	}
+
+	return
+}
+
+// Variance returns the variance of the reduced values, as the mean of the
+// squared deviations to their mean, which needs the mean in every slot:
+// the reduction must span all the slots or set Broadcast. It consumes three levels.
+func (r Reduction) Variance(eval Evaluator, ct *rlwe.Ciphertext) (res *rlwe.Ciphertext, err error) {
+
+	params := *eval.GetParameters()
+
+	batch, n, err := r.Dimensions(params)
+	if err != nil {
+		return
+	}
+
+	if !r.Broadcast && n*batch != params.MaxSlots() {
+		return nil, fmt.Errorf("cannot Variance: the reduction must set Broadcast when it does not span all the slots")
+	}
+
+	var mean *rlwe.Ciphertext
+	if mean, err = r.Mean(eval, ct); err != nil {
+		return
+	}
+
+	if res, err = eval.SubNew(ct, mean); err != nil {
+		return nil, fmt.Errorf("%T.SubNew: %w", eval, err)
+	}
+
+	if err = eval.MulRelin(res, res, res); err != nil {
+		return nil, fmt.Errorf("%T.MulRelin: %w", eval, err)
+	}
+
+	if err = eval.Rescale(res, res); err != nil {
+		return nil, fmt.Errorf("%T.Rescale: %w", eval, err)
+	}
+
+	return r.Mean(eval, res)
+}
+
+// adder returns the combination of Sum.
+func adder(eval Evaluator) func(a, b *rlwe.Ciphertext) (*rlwe.Ciphertext, error) {
+	return func(a, b *rlwe.Ciphertext) (*rlwe.Ciphertext, error) {
+		return eval.AddNew(a, b)
+	}
+}
+
+// reduce combines, in every slot j, the n values of the slots j + t*batch for t < n.
+// pow holds the combination of the 2^i values starting at j, and the windows
+// of the binary decomposition of n are combined, shifted, into acc.
+// It is generic so that Rotations can record the rotations of the same schedule.
+func reduce[T any](x T, batch, n int, rotate func(x T, k int) (T, error), combine func(a, b T) (T, error)) (acc T, err error) {
+
+	pow := x
+
+	var offset int
+	for i := 0; n>>i > 0; i++ {
+
+		if n>>i&1 == 1 {
+
+			if offset == 0 {
+				acc = pow
+			} else {
+
+				var y T
+				if y, err = rotate(pow, offset*batch); err != nil {
+					return
+				}
+
+				if acc, err = combine(acc, y); err != nil {
+					return
+				}
+			}
+
+			offset += 1 << i
+		}
+
+		if n>>(i+1) > 0 {
+
+			var y T
+			if y, err = rotate(pow, (1<<i)*batch); err != nil {
+				return
+			}
+
+			if pow, err = combine(pow, y); err != nil {
+				return
+			}
+		}
+	}
+
+	return
+}
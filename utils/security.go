@@ -0,0 +1,255 @@
+package utils
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+
+	"github.com/tuneinsight/lattigo/v5/ring"
+)
+
+// DefaultMinSecurity is the minimum bit security enforced when the
+// configuration does not set "Security": {"MinBits": ...}.
+const DefaultMinSecurity = 128
+
+// securityLevels are the bit security levels of the columns of the tables below.
+var securityLevels = [3]float64{128, 192, 256}
+
+// Maximum log2(QP) for classical security of 128, 192 and 256 bits, indexed by LogN,
+// from Table 1 of the HomomorphicEncryption.org Security Standard (2018).
+var (
+	maxLogQPUniform = map[int][3]float64{
+		10: {29, 21, 16},
+		11: {56, 39, 31},
+		12: {111, 77, 60},
+		13: {220, 154, 120},
+		14: {440, 307, 239},
+		15: {883, 613, 478},
+	}
+
+	maxLogQPGaussian = map[int][3]float64{
+		10: {29, 21, 16},
+		11: {56, 39, 31},
+		12: {111, 77, 60},
+		13: {220, 154, 120},
+		14: {440, 307, 239},
+		15: {883, 613, 478},
+	}
+
+	maxLogQPTernary = map[int][3]float64{
+		10: {27, 19, 14},
+		11: {54, 37, 29},
+		12: {109, 75, 58},
+		13: {218, 152, 118},
+		14: {438, 305, 237},
+		15: {881, 611, 476},
+	}
+)
+
+const (
+	// minTableLogN and maxTableLogN are the bounds of the standard tables.
+	minTableLogN = 10
+	maxTableLogN = 15
+
+	// extrapolationRatio is the growth of the maximum log2(QP) per doubling of N
+	// above the standard tables: 1793/881, the ratio between the log2(QP) of the
+	// largest 128-bit dense secret bootstrapping parameters of Lattigo for LogN=16
+	// (N16QP1793H32768H32) and the LogN=15 bound of the standard.
+	extrapolationRatio = 1793.0 / 881.0
+
+	// sparseAnchorWeight and sparseAnchorFactor anchor the penalty of sparse ternary
+	// secrets, which the standard does not cover: a secret of Hamming weight 192 is
+	// given 768/880 of the dense ternary budget, the ratio between the log2(QP) of
+	// the 128-bit bootstrapping parameters of Lattigo for LogN=15 with H=192 and
+	// with a dense secret (N15QP768H192H32 and N15QP880H16384H32). The factor is
+	// interpolated in log2(H) between this point and the dense secret (H=N/2).
+	sparseAnchorWeight = 192
+	sparseAnchorFactor = 768.0 / 880.0
+)
+
+// SecurityEstimate is the estimated security of one parameter set.
+type SecurityEstimate struct {
+	// Name is the parameter set: "Scheme", "Bootstrapping" or "EphemeralSecret".
+	Name   string
+	LogN   int
+	LogQP  float64
+	Secret string
+	Bits   float64
+}
+
+func (e SecurityEstimate) String() string {
+	return fmt.Sprintf("%-15s LogN=%2d LogQP=%8.2f Xs=%-24s ~%6.1f bits", e.Name, e.LogN, e.LogQP, e.Secret, e.Bits)
+}
+
+// EstimateSecurity returns the estimated classical bit security of an RLWE
+// instance of ring degree 2^logN, modulus of log2(QP) bits and secret drawn
+// from xs, along with a description of the secret distribution.
+//
+// The estimate interpolates the HomomorphicEncryption.org standard tables
+// linearly in 1/log2(QP), which follows the N/log2(Q) behavior of the lattice
+// attacks between the 128, 192 and 256 bits columns, and extrapolates outside
+// of them and above LogN=15 (see extrapolationRatio). Sparse ternary secrets
+// are penalized with the heuristic described at sparseAnchorFactor, and capped
+// by the entropy of the secret. It is an estimate, not a lattice estimator run.
+func EstimateSecurity(logN int, logQP float64, xs ring.DistributionParameters) (bits float64, secret string, err error) {
+
+	if logN < minTableLogN {
+		return 0, "", fmt.Errorf("cannot estimate security: LogN=%d is below the standard tables (LogN >= %d)", logN, minTableLogN)
+	}
+
+	n := 1 << logN
+
+	var table map[int][3]float64
+	factor := 1.0
+	entropy := math.Inf(1)
+
+	switch xs := xs.(type) {
+	case ring.Ternary:
+
+		h := xs.H
+		if h == 0 {
+			h = int(math.Round(xs.P * float64(n)))
+		}
+
+		if h <= 0 {
+			return 0, "", fmt.Errorf("cannot estimate security: invalid ternary secret %+v", xs)
+		}
+
+		table = maxLogQPTernary
+
+		if dense := n / 2; h < dense {
+			secret = fmt.Sprintf("sparse-ternary(H=%d)", h)
+			factor = 1 - (1-sparseAnchorFactor)*math.Log2(float64(dense)/float64(h))/math.Log2(float64(dense)/sparseAnchorWeight)
+			entropy = logBinomial(n, h) + float64(h)
+		} else {
+			secret = "ternary"
+		}
+
+	case ring.DiscreteGaussian:
+		table = maxLogQPGaussian
+		secret = fmt.Sprintf("gaussian(sigma=%g)", xs.Sigma)
+	case ring.Uniform:
+		table = maxLogQPUniform
+		secret = "uniform"
+	default:
+		return 0, "", fmt.Errorf("cannot estimate security: unsupported secret distribution %T", xs)
+	}
+
+	var bounds [3]float64
+	if logN <= maxTableLogN {
+		bounds = table[logN]
+	} else {
+		scale := math.Pow(extrapolationRatio, float64(logN-maxTableLogN))
+		for i, b := range table[maxTableLogN] {
+			bounds[i] = b * scale
+		}
+	}
+
+	for i := range bounds {
+		bounds[i] *= factor
+	}
+
+	// Piecewise linear interpolation of the security in 1/log2(QP)
+	x := 1 / logQP
+	i := 0
+	if x > 1/bounds[1] {
+		i = 1
+	}
+
+	x0, x1 := 1/bounds[i], 1/bounds[i+1]
+	y0, y1 := securityLevels[i], securityLevels[i+1]
+
+	bits = y0 + (x-x0)*(y1-y0)/(x1-x0)
+
+	return math.Max(0, math.Min(bits, entropy)), secret, nil
+}
+
+// logBinomial returns log2(n choose k).
+func logBinomial(n, k int) float64 {
+	a, _ := math.Lgamma(float64(n + 1))
+	b, _ := math.Lgamma(float64(k + 1))
+	c, _ := math.Lgamma(float64(n - k + 1))
+	return (a - b - c) / math.Ln2
+}
+
+// Security returns the estimated security of the scheme parameters and, if
+// bootstrapping is enabled, of the bootstrapping parameters and of the
+// ephemeral sparse secret (ring of the bootstrapping parameters, modulus Q0*P0).
+func (p Parameters) Security() (estimates []SecurityEstimate, err error) {
+
+	estimate := func(name string, logN int, logQP float64, xs ring.DistributionParameters) (err error) {
+		e := SecurityEstimate{Name: name, LogN: logN, LogQP: logQP}
+		if e.Bits, e.Secret, err = EstimateSecurity(logN, logQP, xs); err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+		estimates = append(estimates, e)
+		return
+	}
+
+	if err = estimate("Scheme", p.Scheme.LogN(), p.Scheme.LogQP(), p.Scheme.Xs()); err != nil {
+		return
+	}
+
+	if p.Bootstrapping == nil {
+		return
+	}
+
+	btp := p.Bootstrapping.BootstrappingParameters
+
+	// In the same ring, the bootstrapping keys are generated under the scheme secret
+	xs := btp.Xs()
+	if btp.N() == p.Scheme.N() {
+		xs = p.Scheme.Xs()
+	}
+
+	if err = estimate("Bootstrapping", btp.LogN(), btp.LogQP(), xs); err != nil {
+		return
+	}
+
+	if h := p.Bootstrapping.EphemeralSecretWeight; h != 0 {
+		logQP := math.Log2(float64(btp.Q()[0])) + math.Log2(float64(btp.P()[0]))
+		if err = estimate("EphemeralSecret", btp.LogN(), logQP, ring.Ternary{H: h}); err != nil {
+			return
+		}
+	}
+
+	return
+}
+
+// CheckSecurity returns the estimates of Security and an error if any of
+// them is below minBits.
+func (p Parameters) CheckSecurity(minBits float64) (estimates []SecurityEstimate, err error) {
+
+	if estimates, err = p.Security(); err != nil {
+		return
+	}
+
+	for _, e := range estimates {
+		if e.Bits < minBits {
+			return estimates, fmt.Errorf("insecure parameters: %s has an estimated security of %.1f bits (LogN=%d, LogQP=%.2f, Xs=%s), below the minimum of %.0f bits", e.Name, e.Bits, e.LogN, e.LogQP, e.Secret, minBits)
+		}
+	}
+
+	return
+}
+
+// MinSecurityFromJSON returns the "MinBits" field of the "Security" block of
+// a configuration file, or DefaultMinSecurity if it is not set.
+func MinSecurityFromJSON(data []byte) (minBits float64, err error) {
+
+	aux := struct {
+		Security struct {
+			MinBits *float64
+		}
+	}{}
+
+	if err = json.Unmarshal(data, &aux); err != nil {
+		return
+	}
+
+	if aux.Security.MinBits == nil {
+		return DefaultMinSecurity, nil
+	}
+
+	return *aux.Security.MinBits, nil
+}
@@ -0,0 +1,311 @@
+package utils
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"fmt"
+
+	"github.com/tuneinsight/lattigo/v5/core/rlwe"
+	"github.com/tuneinsight/lattigo/v5/he/hefloat/bootstrapping"
+	"github.com/tuneinsight/lattigo/v5/ring"
+	"github.com/tuneinsight/lattigo/v5/ring/ringqp"
+	"github.com/tuneinsight/lattigo/v5/utils"
+	"github.com/tuneinsight/lattigo/v5/utils/sampling"
+)
+
+// Every gadget ciphertext of an evaluation key is a pair (-a*s + w*P*s' + e, a)
+// where a is uniform. In a seeded set, the a of the key named n (the names of the index)
+// are drawn from the PRNG keyed with SHA-256(Seed || n) and are not written:
+// the key file holds the seed in its index, and the a are regenerated from it
+// when the keys are read, which halves the size of the file.
+
+// SeedSize is the size in bytes of the seed of a seeded EvaluationKeySet.
+const SeedSize = 32
+
+// NewSeed returns a random seed for a seeded EvaluationKeySet.
+func NewSeed() (seed []byte, err error) {
+	seed = make([]byte, SeedSize)
+	if _, err = rand.Read(seed); err != nil {
+		return nil, fmt.Errorf("crypto/rand.Read: %w", err)
+	}
+	return
+}
+
+// Names of the keys, prefixed by "Bootstrapping." for the relinearization and
+// Galois keys of the bootstrapping.
+const (
+	relinearizationKeyName = "RelinearizationKey"
+	bootstrappingPrefix    = "Bootstrapping."
+)
+
+func galoisKeyName(prefix string, galEl uint64) string {
+	return fmt.Sprintf("%sGaloisKey[%d]", prefix, galEl)
+}
+
+// keyPRNG returns the PRNG of the a of the key name.
+func keyPRNG(seed []byte, name string) (*sampling.KeyedPRNG, error) {
+	key := sha256.Sum256(append(append([]byte{}, seed...), name...))
+	return sampling.NewKeyedPRNG(key[:])
+}
+
+// seededKeyGenerator returns a key generator drawing the a of the key name
+// from the seed. It must generate this key only.
+func seededKeyGenerator(params rlwe.ParameterProvider, seed []byte, name string) (*rlwe.KeyGenerator, error) {
+
+	prng, err := keyPRNG(seed, name)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+
+	return &rlwe.KeyGenerator{Encryptor: rlwe.NewEncryptor(params, nil).WithPRNG(prng)}, nil
+}
+
+// genSeededBootstrappingKeys generates the bootstrapping keys as
+// bootstrapping.Parameters.GenEvaluationKeys does, with one seeded key
+// generator per key.
+func genSeededBootstrappingKeys(params Parameters, seed []byte, skN1 *rlwe.SecretKey) (btp *bootstrapping.EvaluationKeys, err error) {
+
+	p := params.Bootstrapping
+	paramsN2 := p.BootstrappingParameters
+
+	kgen := func(params rlwe.ParameterProvider, name string) (kgen *rlwe.KeyGenerator) {
+		if err == nil {
+			kgen, err = seededKeyGenerator(params, seed, name)
+		}
+		return
+	}
+
+	btp = &bootstrapping.EvaluationKeys{}
+
+	var skN2 *rlwe.SecretKey
+
+	if p.ResidualParameters.N() != paramsN2.N() {
+
+		skN2 = rlwe.NewKeyGenerator(paramsN2).GenSecretKeyNew()
+
+		if p.ResidualParameters.RingType() == ring.ConjugateInvariant {
+
+			// As rlwe.KeyGenerator.GenEvaluationKeysForRingSwapNew, which generates both keys with the same generator
+			ringQ := paramsN2.RingQ()
+			levelQ := utils.Min(skN2.Value.Q.Level(), skN1.Value.Q.Level())
+
+			skCI := &rlwe.SecretKey{Value: paramsN2.RingQP().AtLevel(levelQ, paramsN2.MaxLevelP()).NewPoly()}
+			ringQ.AtLevel(levelQ).UnfoldConjugateInvariantToStandard(skN1.Value.Q, skCI.Value.Q)
+
+			if paramsN2.PCount() != 0 {
+				rlwe.ExtendBasisSmallNormAndCenterNTTMontgomery(ringQ, paramsN2.RingP(), skCI.Value.Q, ringQ.NewPoly(), skCI.Value.P)
+			}
+
+			btp.EvkCmplxToReal = rlwe.NewEvaluationKey(paramsN2)
+			btp.EvkRealToCmplx = rlwe.NewEvaluationKey(paramsN2)
+
+			if g := kgen(paramsN2, "EvkCmplxToReal"); err == nil {
+				g.GenEvaluationKey(skN2, skCI, btp.EvkCmplxToReal)
+			}
+
+			if g := kgen(paramsN2, "EvkRealToCmplx"); err == nil {
+				g.GenEvaluationKey(skCI, skN2, btp.EvkRealToCmplx)
+			}
+
+		} else {
+
+			if g := kgen(paramsN2, "EvkN1ToN2"); err == nil {
+				btp.EvkN1ToN2 = g.GenEvaluationKeyNew(skN1, skN2)
+			}
+
+			if g := kgen(paramsN2, "EvkN2ToN1"); err == nil {
+				btp.EvkN2ToN1 = g.GenEvaluationKeyNew(skN2, skN1)
+			}
+		}
+
+	} else {
+
+		// Same secret, extended to the full modulus of the bootstrapping parameters
+		ringQ := paramsN2.RingQ()
+		skN2 = rlwe.NewSecretKey(paramsN2)
+		buff := ringQ.NewPoly()
+		rlwe.ExtendBasisSmallNormAndCenterNTTMontgomery(ringQ, ringQ, skN1.Value.Q, buff, skN2.Value.Q)
+		rlwe.ExtendBasisSmallNormAndCenterNTTMontgomery(ringQ, paramsN2.RingP(), skN1.Value.Q, buff, skN2.Value.P)
+	}
+
+	if p.EphemeralSecretWeight != 0 {
+
+		var paramsSparse rlwe.Parameters
+		if paramsSparse, err = rlwe.NewParametersFromLiteral(rlwe.ParametersLiteral{
+			LogN: paramsN2.LogN(),
+			Q:    paramsN2.Q()[:1],
+			P:    paramsN2.P()[:1],
+		}); err != nil {
+			return nil, fmt.Errorf("ephemeral secret parameters: %w", err)
+		}
+
+		skSparse := rlwe.NewKeyGenerator(paramsSparse).GenSecretKeyWithHammingWeightNew(p.EphemeralSecretWeight)
+
+		if g := kgen(paramsN2, "EvkDenseToSparse"); err == nil {
+			btp.EvkDenseToSparse = g.GenEvaluationKeyNew(skN2, skSparse)
+		}
+
+		if g := kgen(paramsN2, "EvkSparseToDense"); err == nil {
+			btp.EvkSparseToDense = g.GenEvaluationKeyNew(skSparse, skN2)
+		}
+	}
+
+	var rlk *rlwe.RelinearizationKey
+	if g := kgen(paramsN2, bootstrappingPrefix+relinearizationKeyName); err == nil {
+		rlk = g.GenRelinearizationKeyNew(skN2)
+	}
+
+	galEls := append(p.GaloisElements(paramsN2), paramsN2.GaloisElementForComplexConjugation())
+	gks := make([]*rlwe.GaloisKey, len(galEls))
+	for i, galEl := range galEls {
+		if g := kgen(paramsN2, galoisKeyName(bootstrappingPrefix, galEl)); err == nil {
+			gks[i] = g.GenGaloisKeyNew(galEl, skN2)
+		}
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	btp.MemEvaluationKeySet = rlwe.NewMemEvaluationKeySet(rlk, gks...)
+
+	return
+}
+
+// compressKey returns a shallow copy of the key without its a.
+func compressKey(key serializableKey) serializableKey {
+
+	compress := func(evk rlwe.EvaluationKey) rlwe.EvaluationKey {
+		value := make([][]rlwe.VectorQP, len(evk.Value))
+		for i := range evk.Value {
+			value[i] = make([]rlwe.VectorQP, len(evk.Value[i]))
+			for j := range evk.Value[i] {
+				value[i][j] = evk.Value[i][j][:1]
+			}
+		}
+		evk.Value = value
+		return evk
+	}
+
+	switch key := key.(type) {
+	case *rlwe.GaloisKey:
+		gk := *key
+		gk.EvaluationKey = compress(key.EvaluationKey)
+		return &gk
+	case *rlwe.RelinearizationKey:
+		return &rlwe.RelinearizationKey{EvaluationKey: compress(key.EvaluationKey)}
+	case *rlwe.EvaluationKey:
+		evk := compress(*key)
+		return &evk
+	default:
+		return key
+	}
+}
+
+// expandKey regenerates the a of the compressed key name in the ring ringQP.
+func expandKey(ringQP ringqp.Ring, seed []byte, name string, evk *rlwe.EvaluationKey) (err error) {
+
+	prng, err := keyPRNG(seed, name)
+	if err != nil {
+		return fmt.Errorf("%s: %w", name, err)
+	}
+
+	// Same sequence of reads as rlwe.Encryptor.EncryptZero in rlwe.KeyGenerator
+	sampler := ringqp.NewUniformSampler(prng, ringQP)
+
+	for i := range evk.Value {
+		for j := range evk.Value[i] {
+
+			if len(evk.Value[i][j]) != 1 {
+				return fmt.Errorf("%s: expected a seeded key with one polynomial per gadget ciphertext, but it has %d", name, len(evk.Value[i][j]))
+			}
+
+			b := evk.Value[i][j][0]
+
+			if b.Q.N() != ringQP.N() {
+				return fmt.Errorf("%s: ring degree %d does not match the parameters N=%d", name, b.Q.N(), ringQP.N())
+			}
+
+			levelQ, levelP := b.LevelQ(), b.LevelP()
+			a := ringQP.AtLevel(levelQ, levelP).NewPoly()
+			sampler.AtLevel(levelQ, levelP).Read(a)
+
+			evk.Value[i][j] = append(evk.Value[i][j], a)
+		}
+	}
+
+	return
+}
+
+// expandKeySet regenerates the a of the relinearization and Galois keys of a compressed set.
+func expandKeySet(ringQP ringqp.Ring, seed []byte, prefix string, set *rlwe.MemEvaluationKeySet) (err error) {
+
+	if set.RelinearizationKey != nil {
+		if err = expandKey(ringQP, seed, prefix+relinearizationKeyName, &set.RelinearizationKey.EvaluationKey); err != nil {
+			return
+		}
+	}
+
+	for galEl, gk := range set.GaloisKeys {
+		if err = expandKey(ringQP, seed, galoisKeyName(prefix, galEl), &gk.EvaluationKey); err != nil {
+			return
+		}
+	}
+
+	return
+}
+
+// expandBootstrappingKeys regenerates the a of compressed bootstrapping keys.
+func expandBootstrappingKeys(params Parameters, seed []byte, btp *bootstrapping.EvaluationKeys) (err error) {
+
+	if params.Bootstrapping == nil {
+		return fmt.Errorf("cannot expand the seeded bootstrapping keys: bootstrapping is not enabled in the parameters")
+	}
+
+	ringQP := *params.Bootstrapping.BootstrappingParameters.RingQP()
+
+	for _, key := range []struct {
+		name string
+		evk  *rlwe.EvaluationKey
+	}{
+		{"EvkN1ToN2", btp.EvkN1ToN2},
+		{"EvkN2ToN1", btp.EvkN2ToN1},
+		{"EvkRealToCmplx", btp.EvkRealToCmplx},
+		{"EvkCmplxToReal", btp.EvkCmplxToReal},
+		{"EvkDenseToSparse", btp.EvkDenseToSparse},
+		{"EvkSparseToDense", btp.EvkSparseToDense},
+	} {
+		if key.evk != nil {
+			if err = expandKey(ringQP, seed, key.name, key.evk); err != nil {
+				return
+			}
+		}
+	}
+
+	if btp.MemEvaluationKeySet != nil {
+		return expandKeySet(ringQP, seed, bootstrappingPrefix, btp.MemEvaluationKeySet)
+	}
+
+	return
+}
+
+// Expand regenerates the a of the keys of a seeded set read with ReadFrom,
+// which leaves them compressed. Deserialize calls it with its parameters.
+func (evk *EvaluationKeySet) Expand(params Parameters) (err error) {
+
+	if evk.Seed == nil {
+		return
+	}
+
+	if set, ok := evk.Scheme.(*rlwe.MemEvaluationKeySet); ok {
+		if err = expandKeySet(*params.Scheme.RingQP(), evk.Seed, "", set); err != nil {
+			return
+		}
+	}
+
+	if evk.Bootstrapping != nil {
+		return expandBootstrappingKeys(params, evk.Seed, evk.Bootstrapping)
+	}
+
+	return
+}
@@ -1,13 +1,100 @@
 package utils
 
 import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
 	"encoding"
+	"encoding/binary"
 	"fmt"
+	"hash/crc32"
 	"io"
 	"os"
+
+	"github.com/tuneinsight/lattigo/v5/core/rlwe"
 )
 
-func Serialize(object interface{}, path string) (err error) {
+// Files written by Serialize are framed as follows:
+//
+//	magic          [4]byte  "FHRM"
+//	version        uint8    FormatVersion
+//	kind           uint8    Kind of the object
+//	parameters     [32]byte SHA-256 of the parameters the object belongs to (zero if none)
+//	payload length uint64
+//	payload        []byte   WriteTo or MarshalBinary of the object
+//	checksum       uint32   CRC32 (IEEE) of the payload
+//
+// All integers are little endian.
+
+// FormatVersion is the version of the framing written by Serialize.
+// Version 2 indexes the payload of EvaluationKeySet (see keyfile.go).
+const FormatVersion = 2
+
+var magic = [4]byte{'F', 'H', 'R', 'M'}
+
+const headerSize = 4 + 1 + 1 + sha256.Size + 8
+
+// Kind identifies the type of a serialized object.
+type Kind uint8
+
+const (
+	KindOther Kind = iota
+	KindParameters
+	KindSecretKey
+	KindPublicKey
+	KindEvaluationKeySet
+	KindCiphertext
+	KindPlaintext
+	KindCiphertexts
+)
+
+func (k Kind) String() string {
+	switch k {
+	case KindParameters:
+		return "Parameters"
+	case KindSecretKey:
+		return "SecretKey"
+	case KindPublicKey:
+		return "PublicKey"
+	case KindEvaluationKeySet:
+		return "EvaluationKeySet"
+	case KindCiphertext:
+		return "Ciphertext"
+	case KindPlaintext:
+		return "Plaintext"
+	case KindCiphertexts:
+		return "Ciphertexts"
+	default:
+		return fmt.Sprintf("Kind(%d)", uint8(k))
+	}
+}
+
+// KindOf returns the Kind of an object.
+func KindOf(object interface{}) Kind {
+	switch object.(type) {
+	case Parameters, *Parameters:
+		return KindParameters
+	case rlwe.SecretKey, *rlwe.SecretKey:
+		return KindSecretKey
+	case rlwe.PublicKey, *rlwe.PublicKey:
+		return KindPublicKey
+	case EvaluationKeySet, *EvaluationKeySet:
+		return KindEvaluationKeySet
+	case rlwe.Ciphertext, *rlwe.Ciphertext:
+		return KindCiphertext
+	case rlwe.Plaintext, *rlwe.Plaintext:
+		return KindPlaintext
+	case Ciphertexts, *Ciphertexts:
+		return KindCiphertexts
+	default:
+		return KindOther
+	}
+}
+
+// Serialize writes the object to path, framed with a header and a checksum.
+// The header records the hash of params, which can be nil if the object does
+// not belong to any parameters. Parameters are always hashed themselves.
+func Serialize(object interface{}, path string, params *Parameters) (err error) {
 
 	f, err := os.Create(path)
 	if err != nil {
@@ -16,52 +103,200 @@ func Serialize(object interface{}, path string) (err error) {
 
 	defer f.Close()
 
+	header := make([]byte, headerSize)
+	copy(header, magic[:])
+	header[4] = FormatVersion
+	header[5] = uint8(KindOf(object))
+
+	var paramsHash [sha256.Size]byte
+	if paramsHash, err = objectParametersHash(object, params); err != nil {
+		return
+	}
+	copy(header[6:], paramsHash[:])
+
+	// The payload length is patched once the payload is written.
+	if _, err = f.Write(header); err != nil {
+		return fmt.Errorf("file.Write: %w", err)
+	}
+
+	crc := crc32.NewIEEE()
+	payload := &countingWriter{w: io.MultiWriter(f, crc)}
+
 	switch object := object.(type) {
 	case io.WriterTo:
-		if _, err = object.WriteTo(f); err != nil {
+		w := bufio.NewWriter(payload)
+		if _, err = object.WriteTo(w); err != nil {
 			return fmt.Errorf("%T.WriteTo: %w", object, err)
 		}
+		if err = w.Flush(); err != nil {
+			return fmt.Errorf("bufio.Writer.Flush: %w", err)
+		}
 	case encoding.BinaryMarshaler:
 		var data []byte
 		if data, err = object.MarshalBinary(); err != nil {
 			return fmt.Errorf("%T.MarshalBinary: %w", object, err)
 		}
-		if _, err = f.Write(data); err != nil {
+		if _, err = payload.Write(data); err != nil {
 			return fmt.Errorf("file.Write: %w", err)
 		}
 	default:
 		return fmt.Errorf("%T does not implement io.WriterTo or encoding.BinaryMarshaler", object)
 	}
 
+	binary.LittleEndian.PutUint64(header[headerSize-8:], payload.n)
+
+	if _, err = f.WriteAt(header[headerSize-8:], headerSize-8); err != nil {
+		return fmt.Errorf("file.WriteAt: %w", err)
+	}
+
+	if err = binary.Write(f, binary.LittleEndian, crc.Sum32()); err != nil {
+		return fmt.Errorf("binary.Write: %w", err)
+	}
+
 	return
 }
 
-func Deserialize(object interface{}, path string) (err error) {
+// Deserialize reads the object from a file written by Serialize.
+// It returns an error if the file does not hold an object of the same kind,
+// if params is not nil and the object belongs to other parameters, or if the
+// checksum does not match. The keys of a seeded EvaluationKeySet are expanded
+// with params, which must then be given.
+func Deserialize(object interface{}, path string, params *Parameters) (err error) {
+
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("os.Open(%s): %w", path, err)
+	}
+	defer f.Close()
+
+	size, err := readHeader(f, path, KindOf(object), params)
+	if err != nil {
+		return
+	}
+
+	crc := crc32.NewIEEE()
+	r := bufio.NewReader(io.TeeReader(io.LimitReader(f, int64(size)), crc))
 
 	switch object := object.(type) {
 	case io.ReaderFrom:
-		f, err := os.Open(path)
-		if err != nil {
-			return fmt.Errorf("os.Open(%s): %w", path, err)
-		}
-		defer f.Close()
-
-		if _, err = object.ReadFrom(f); err != nil {
+		if _, err = object.ReadFrom(r); err != nil {
 			return fmt.Errorf("%T.ReadFrom: %w", object, err)
 		}
 	case encoding.BinaryUnmarshaler:
 		var data []byte
-		if data, err = os.ReadFile(path); err != nil {
-			return fmt.Errorf("os.ReadFile(%s): %w", path, err)
+		if data, err = io.ReadAll(r); err != nil {
+			return fmt.Errorf("io.ReadAll: %w", err)
 		}
 
 		if err = object.UnmarshalBinary(data); err != nil {
 			return fmt.Errorf("%T.UnmarshalBinary: %w", object, err)
 		}
-
 	default:
 		return fmt.Errorf("%T does not implement io.ReaderFrom or encoding.BinaryUnmarshaler", object)
 	}
 
+	var trailing int64
+	if trailing, err = io.Copy(io.Discard, r); err != nil {
+		return fmt.Errorf("%s: cannot read payload: %w", path, err)
+	}
+
+	if trailing != 0 {
+		return fmt.Errorf("%s: %d trailing bytes after the %s payload", path, trailing, KindOf(object))
+	}
+
+	var checksum uint32
+	if err = binary.Read(f, binary.LittleEndian, &checksum); err != nil {
+		return fmt.Errorf("%s: cannot read checksum: %w", path, err)
+	}
+
+	if checksum != crc.Sum32() {
+		return fmt.Errorf("%s: checksum mismatch, the file is corrupted", path)
+	}
+
+	if evk, ok := object.(*EvaluationKeySet); ok && evk.Seed != nil {
+
+		if params == nil {
+			return fmt.Errorf("%s: the evaluation keys are seeded and need the parameters to be expanded", path)
+		}
+
+		if err = evk.Expand(*params); err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+	}
+
+	if p, ok := object.(*Parameters); ok {
+		if _, err = f.Seek(6, io.SeekStart); err != nil {
+			return fmt.Errorf("file.Seek: %w", err)
+		}
+
+		var paramsHash [sha256.Size]byte
+		if _, err = io.ReadFull(f, paramsHash[:]); err != nil {
+			return fmt.Errorf("%s: cannot read header: %w", path, err)
+		}
+
+		if paramsHash != p.Hash() {
+			return fmt.Errorf("%s: parameters hash mismatch, the file is corrupted", path)
+		}
+	}
+
+	return
+}
+
+// readHeader reads and checks the header of a file written by Serialize,
+// and returns the size of the payload that follows it.
+func readHeader(f io.Reader, path string, kind Kind, params *Parameters) (size uint64, err error) {
+
+	header := make([]byte, headerSize)
+	if _, err = io.ReadFull(f, header); err != nil {
+		return 0, fmt.Errorf("%s: cannot read header: %w", path, err)
+	}
+
+	if !bytes.Equal(header[:4], magic[:]) {
+		return 0, fmt.Errorf("%s: not a serialized object (invalid magic number %q)", path, header[:4])
+	}
+
+	if header[4] != FormatVersion {
+		return 0, fmt.Errorf("%s: unsupported format version %d, expected %d", path, header[4], FormatVersion)
+	}
+
+	if have := Kind(header[5]); have != kind {
+		return 0, fmt.Errorf("%s: file contains an object of kind %s, expected %s", path, have, kind)
+	}
+
+	var paramsHash [sha256.Size]byte
+	copy(paramsHash[:], header[6:])
+
+	if params != nil && kind != KindParameters && paramsHash != [sha256.Size]byte{} {
+		if want := params.Hash(); paramsHash != want {
+			return 0, fmt.Errorf("%s: %s belongs to parameters %x, but the loaded parameters are %x", path, kind, paramsHash[:8], want[:8])
+		}
+	}
+
+	return binary.LittleEndian.Uint64(header[headerSize-8:]), nil
+}
+
+func objectParametersHash(object interface{}, params *Parameters) (h [sha256.Size]byte, err error) {
+	switch object := object.(type) {
+	case Parameters:
+		return object.Hash(), nil
+	case *Parameters:
+		return object.Hash(), nil
+	}
+
+	if params != nil {
+		return params.Hash(), nil
+	}
+
+	return
+}
+
+type countingWriter struct {
+	w io.Writer
+	n uint64
+}
+
+func (c *countingWriter) Write(p []byte) (n int, err error) {
+	n, err = c.w.Write(p)
+	c.n += uint64(n)
 	return
 }
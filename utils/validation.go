@@ -0,0 +1,226 @@
+package utils
+
+import (
+	"fmt"
+
+	"github.com/tuneinsight/lattigo/v5/core/rlwe"
+	"github.com/tuneinsight/lattigo/v5/he/hefloat"
+	"github.com/tuneinsight/lattigo/v5/he/hefloat/bootstrapping"
+)
+
+// CheckCompatibility returns an error describing the first mismatch found
+// between the parameters, the evaluation keys and the ciphertexts.
+func CheckCompatibility(params Parameters, evk EvaluationKeySet, cts ...*rlwe.Ciphertext) (err error) {
+
+	if params.Bootstrapping != nil && !params.Bootstrapping.ResidualParameters.Equal(&params.Scheme) {
+		return fmt.Errorf("parameters: the residual parameters of the bootstrapping do not match the scheme parameters")
+	}
+
+	if err = CheckEvaluationKeySet(params, evk); err != nil {
+		return
+	}
+
+	for i, ct := range cts {
+		if err = CheckCiphertext(params.Scheme, ct); err != nil {
+			return fmt.Errorf("ciphertext %d: %w", i, err)
+		}
+	}
+
+	return
+}
+
+// CheckCiphertexts returns an error describing the first ciphertext of the
+// collection that cannot be evaluated with the given parameters.
+func CheckCiphertexts(params hefloat.Parameters, c Ciphertexts) (err error) {
+
+	for _, name := range c.Names() {
+
+		if len(c[name]) == 0 {
+			return fmt.Errorf("%s: the vector has no ciphertext", name)
+		}
+
+		for i, ct := range c[name] {
+			if err = CheckCiphertext(params, ct); err != nil {
+				return fmt.Errorf("%s[%d]: %w", name, i, err)
+			}
+		}
+	}
+
+	return
+}
+
+// CheckCiphertext returns an error if the ciphertext cannot be
+// evaluated with the given parameters.
+func CheckCiphertext(params hefloat.Parameters, ct *rlwe.Ciphertext) (err error) {
+
+	if ct == nil || len(ct.Value) == 0 {
+		return fmt.Errorf("ciphertext is empty")
+	}
+
+	if ct.MetaData == nil {
+		return fmt.Errorf("ciphertext has no metadata")
+	}
+
+	level := ct.Level()
+
+	for i, pol := range ct.Value {
+		if pol.N() != params.N() {
+			return fmt.Errorf("ring degree of polynomial %d is %d but the parameters have N=%d (LogN=%d)", i, pol.N(), params.N(), params.LogN())
+		}
+
+		if pol.Level() != level {
+			return fmt.Errorf("polynomial %d is at level %d but polynomial 0 is at level %d", i, pol.Level(), level)
+		}
+	}
+
+	if level > params.MaxLevel() {
+		return fmt.Errorf("level %d exceeds the parameters MaxLevel=%d (%d moduli in LogQ)", level, params.MaxLevel(), params.QCount())
+	}
+
+	if ct.IsNTT != params.NTTFlag() {
+		return fmt.Errorf("IsNTT=%t but the parameters have NTTFlag=%t", ct.IsNTT, params.NTTFlag())
+	}
+
+	if max := params.LogMaxDimensions(); ct.LogDimensions.Rows > max.Rows || ct.LogDimensions.Cols > max.Cols {
+		return fmt.Errorf("dimensions 2^%dx2^%d exceed the parameters maximum 2^%dx2^%d", ct.LogDimensions.Rows, ct.LogDimensions.Cols, max.Rows, max.Cols)
+	}
+
+	return
+}
+
+// CheckEvaluationKeySet returns an error if the scheme or the bootstrapping
+// evaluation keys do not belong to the rings of the parameters.
+//
+// The keys of a KeyFile are not read: only its index is checked, and each
+// key is checked when it is read.
+func CheckEvaluationKeySet(params Parameters, evk EvaluationKeySet) (err error) {
+
+	kf, lazy := evk.Scheme.(*KeyFile)
+
+	if lazy {
+		if err = kf.check(params); err != nil {
+			return
+		}
+	} else if evk.Scheme != nil {
+		if err = checkKeySet(params.Scheme.Parameters.Parameters, evk.Scheme); err != nil {
+			return fmt.Errorf("scheme evaluation keys: %w", err)
+		}
+	}
+
+	if params.Bootstrapping == nil || (lazy && evk.Bootstrapping == nil) {
+		return
+	}
+
+	if evk.Bootstrapping == nil {
+		return fmt.Errorf("bootstrapping is enabled but the evaluation key set has no bootstrapping keys")
+	}
+
+	return checkBootstrappingKeys(params, evk.Bootstrapping)
+}
+
+// checkBootstrappingKeys returns an error if the bootstrapping evaluation
+// keys do not belong to the rings of the bootstrapping parameters.
+func checkBootstrappingKeys(params Parameters, btp *bootstrapping.EvaluationKeys) (err error) {
+
+	paramsN2 := params.Bootstrapping.BootstrappingParameters
+
+	keys := []struct {
+		name string
+		evk  *rlwe.EvaluationKey
+	}{
+		{"EvkN1ToN2", btp.EvkN1ToN2},
+		{"EvkN2ToN1", btp.EvkN2ToN1},
+		{"EvkRealToCmplx", btp.EvkRealToCmplx},
+		{"EvkCmplxToReal", btp.EvkCmplxToReal},
+		{"EvkDenseToSparse", btp.EvkDenseToSparse},
+		{"EvkSparseToDense", btp.EvkSparseToDense},
+	}
+
+	for _, key := range keys {
+		if key.evk == nil {
+			continue
+		}
+
+		if N := gadgetRingDegree(&key.evk.GadgetCiphertext); N != paramsN2.N() {
+			return fmt.Errorf("bootstrapping evaluation keys: %s has ring degree %d but Bootstrapping.LogN=%d (N=%d)", key.name, N, paramsN2.LogN(), paramsN2.N())
+		}
+	}
+
+	if params.Scheme.N() != paramsN2.N() {
+		if btp.EvkN1ToN2 == nil && btp.EvkCmplxToReal == nil {
+			return fmt.Errorf("bootstrapping evaluation keys: the ring degrees of the scheme (N=%d) and of the bootstrapping (N=%d) differ but the ring switching keys are missing", params.Scheme.N(), paramsN2.N())
+		}
+	}
+
+	if btp.MemEvaluationKeySet == nil {
+		return fmt.Errorf("bootstrapping evaluation keys: relinearization and Galois keys are missing")
+	}
+
+	if err = checkKeySet(paramsN2.Parameters.Parameters, btp.MemEvaluationKeySet); err != nil {
+		return fmt.Errorf("bootstrapping evaluation keys: %w", err)
+	}
+
+	return
+}
+
+func checkKeySet(params rlwe.Parameters, evk rlwe.EvaluationKeySet) (err error) {
+
+	if rlk, err := evk.GetRelinearizationKey(); err == nil {
+		if err = checkEvaluationKey(params, &rlk.EvaluationKey); err != nil {
+			return fmt.Errorf("relinearization key: %w", err)
+		}
+	}
+
+	NthRoot := params.RingQ().NthRoot()
+
+	for _, galEl := range evk.GetGaloisKeysList() {
+
+		gk, err := evk.GetGaloisKey(galEl)
+		if err != nil {
+			return err
+		}
+
+		if gk.GaloisElement != galEl {
+			return fmt.Errorf("Galois key indexed by %d is for the Galois element %d", galEl, gk.GaloisElement)
+		}
+
+		if galEl&1 == 0 || galEl >= NthRoot {
+			return fmt.Errorf("Galois element %d is not an odd integer smaller than the ring NthRoot=%d", galEl, NthRoot)
+		}
+
+		if gk.NthRoot != NthRoot {
+			return fmt.Errorf("Galois key %d is for the NthRoot=%d but the ring has NthRoot=%d", galEl, gk.NthRoot, NthRoot)
+		}
+
+		if err = checkEvaluationKey(params, &gk.EvaluationKey); err != nil {
+			return fmt.Errorf("Galois key %d: %w", galEl, err)
+		}
+	}
+
+	return
+}
+
+func checkEvaluationKey(params rlwe.Parameters, evk *rlwe.EvaluationKey) (err error) {
+
+	if N := gadgetRingDegree(&evk.GadgetCiphertext); N != params.N() {
+		return fmt.Errorf("ring degree %d does not match the parameters N=%d (LogN=%d)", N, params.N(), params.LogN())
+	}
+
+	if evk.LevelQ() > params.MaxLevelQ() {
+		return fmt.Errorf("level Q=%d exceeds the parameters MaxLevelQ=%d", evk.LevelQ(), params.MaxLevelQ())
+	}
+
+	if evk.LevelP() > params.MaxLevelP() {
+		return fmt.Errorf("level P=%d exceeds the parameters MaxLevelP=%d", evk.LevelP(), params.MaxLevelP())
+	}
+
+	return
+}
+
+// gadgetRingDegree returns the ring degree of the gadget ciphertext, or 0 if it is empty.
+func gadgetRingDegree(ct *rlwe.GadgetCiphertext) int {
+	if len(ct.Value) == 0 || len(ct.Value[0]) == 0 || len(ct.Value[0][0]) == 0 {
+		return 0
+	}
+	return ct.Value[0][0][0].Q.N()
+}
@@ -1,12 +1,15 @@
 package main
 
 import (
+	"app/internal/challenge"
+	"app/internal/report"
 	"app/utils"
 	"flag"
 	"fmt"
 	"log"
-	"math/rand"
-	"math/cmplx"
+	"os"
+	"strings"
+	"time"
 
 	"github.com/tuneinsight/lattigo/v5/core/rlwe"
 	"github.com/tuneinsight/lattigo/v5/he/hefloat"
@@ -16,44 +19,123 @@ func main() {
 	ccFile := flag.String("cc", "", "")
 	skFile := flag.String("sk", "", "")
 	outputFile := flag.String("output", "", "")
+	runtimeFile := flag.String("runtime", "", "file with the runtime of the solution, as written by main.go")
+	reportFile := flag.String("report", "", "file to write the JSON report to")
+	minPrecision := flag.Float64("min-prec", -1, "minimum precision in bits, overrides the challenge threshold if non-negative")
+	worst := flag.Int("worst", 8, "number of worst slots in the report")
+	testcase := flag.Int("case", -1, "testcase of the \"Suite\" block of config.json, the \"Challenge\" block as is if negative")
 
 	flag.Parse()
 
 	params := utils.Parameters{}
-	if err := utils.Deserialize(&params, *ccFile); err != nil {
+	if err := utils.Deserialize(&params, *ccFile, nil); err != nil {
 		log.Fatalf(err.Error())
 	}
 
 	sk := rlwe.SecretKey{}
-	if err := utils.Deserialize(&sk, *skFile); err != nil {
+	if err := utils.Deserialize(&sk, *skFile, &params); err != nil {
 		log.Fatalf(err.Error())
 	}
 
-	out := rlwe.Ciphertext{}
-	if err := utils.Deserialize(&out, *outputFile); err != nil {
+	out := utils.Ciphertexts{}
+	if err := utils.Deserialize(&out, *outputFile, &params); err != nil {
 		log.Fatalf(err.Error())
 	}
 
 	dec := rlwe.NewDecryptor(params.Scheme, &sk)
 	ecd := hefloat.NewEncoder(params.Scheme)
 
-	have := make([]complex128, out.Slots())
-	if err := ecd.Decode(dec.DecryptNew(&out), have); err != nil {
-		log.Fatalf("%T.Decode: %s", ecd, err.Error())
+	dataJSON, err := os.ReadFile("config.json")
+	if err != nil {
+		log.Fatalf("os.Open(%s): %s", "config.json", err.Error())
 	}
 
-	r := rand.New(rand.NewSource(0))
-	want := make([]complex128, params.Scheme.MaxSlots())
-	for i := range want {
-		want[i] = complex(2*r.Float64()-1, 2*r.Float64()-1)
+	lit, err := challenge.TestcaseFromJSON(dataJSON, *testcase)
+	if err != nil {
+		log.Fatalf("challenge.TestcaseFromJSON: %s", err.Error())
 	}
 
-	for i := range want{
-		want[i] = cmplx.Conj(want[i])
+	if *minPrecision >= 0 {
+		lit.MinPrecision = minPrecision
 	}
 
-	fmt.Println(have[:4])
-	fmt.Println(want[:4])
+	ch, err := lit.Challenge()
+	if err != nil {
+		log.Fatalf("challenge.Literal.Challenge: %s", err.Error())
+	}
+
+	if err := challenge.CheckParameters(ch, params.Scheme); err != nil {
+		log.Fatalf("challenge.CheckParameters: %s", err.Error())
+	}
+
+	in, err := ch.GenInput(params.Scheme, lit.Rand())
+	if err != nil {
+		log.Fatalf("challenge.Challenge.GenInput: %s", err.Error())
+	}
+
+	want := ch.Reference(in)
+
+	slots := ch.Layout(params.Scheme).Slots
+
+	var outputs []report.Vector
+	level, logScale := params.Scheme.MaxLevel()+1, 0.0
+
+	for _, name := range want.Names() {
+
+		v, err := out.Get(name)
+		if err != nil {
+			log.Fatalf("missing output: %s", err.Error())
+		}
+
+		if n := (len(want[name]) + slots - 1) / slots; len(v) != n {
+			log.Fatalf("output %s: %d ciphertext(s) for %d values with %d values per ciphertext, expected %d", name, len(v), len(want[name]), slots, n)
+		}
+
+		have, err := utils.DecryptVector(params.Scheme, ecd, dec, v, slots)
+		if err != nil {
+			log.Fatalf("output %s: utils.DecryptVector: %s", name, err.Error())
+		}
+
+		have = have[:len(want[name])]
 
-	fmt.Println(hefloat.GetPrecisionStats(params.Scheme, ecd, nil, have, want, 0, false).String())
+		fmt.Println(name, have[:min(4, len(have))])
+		fmt.Println(name, want[name][:min(4, len(have))])
+
+		fmt.Println(hefloat.GetPrecisionStats(params.Scheme, ecd, nil, have, want[name], 0, false).String())
+
+		outputs = append(outputs, report.Vector{Name: name, Have: have, Want: want[name]})
+
+		for _, ct := range v {
+			if ct.Level() < level {
+				level, logScale = ct.Level(), ct.LogScale()
+			}
+		}
+	}
+
+	rep := report.New(lit.Name, outputs, ch.Threshold(), *worst)
+	rep.Level = level
+	rep.LogScale = logScale
+
+	if *runtimeFile != "" {
+		data, err := os.ReadFile(*runtimeFile)
+		if err != nil {
+			log.Fatalf("os.ReadFile(%s): %s", *runtimeFile, err.Error())
+		}
+
+		if rep.Runtime, err = time.ParseDuration(strings.TrimSpace(string(data))); err != nil {
+			log.Fatalf("time.ParseDuration: %s", err.Error())
+		}
+	}
+
+	if *reportFile != "" {
+		if err := rep.WriteFile(*reportFile); err != nil {
+			log.Fatalf("report.Report.WriteFile: %s", err.Error())
+		}
+	}
+
+	fmt.Println(rep.String())
+
+	if !rep.Passed {
+		os.Exit(1)
+	}
 }